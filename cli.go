@@ -0,0 +1,680 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// cliCommand is a subcommand run instead of the TUI, e.g. "go-do-it add".
+type cliCommand struct {
+	name     string
+	args     string
+	summary  string
+	readOnly bool // opens the list without taking the lock if it is in use
+	run      func(c *cli, args []string) error
+}
+
+var cliCommands = []cliCommand{
+	{"add", "[flags] TEXT...", "add a todo; TEXT uses the quick-add syntax", false, cmdAdd},
+	{"list", "[flags]", "list todos", true, cmdList},
+	{"done", "[flags] ID...", "tick todos off (--undo to untick)", false, cmdDone},
+	{"edit", "[flags] ID", "change a todo", false, cmdEdit},
+	{"rm", "[flags] ID...", "delete todos and their subtasks", false, cmdRm},
+	{"tags", "[flags]", "list tags with how many todos have each", true, cmdTags},
+	{"import", "[flags] FILE", "add todos from another tool's file (- for stdin)", false, cmdImport},
+	{"export", "[flags]", "write the list in another tool's format", true, cmdExport},
+}
+
+// usageError is a mistake in how a command was called; it exits with 2.
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+// cli is the state shared by the subcommands: the open list and where
+// output goes.
+type cli struct {
+	store  Store
+	todos  []Todo
+	out    io.Writer
+	errOut io.Writer
+	json   bool
+}
+
+// runCommand runs the subcommand in args against the named list and
+// returns the exit code.
+func runCommand(lists *listSet, list string, args []string) int {
+	if args[0] == "help" {
+		printCommandUsage(os.Stdout)
+		return 0
+	}
+	i := slices.IndexFunc(cliCommands, func(c cliCommand) bool { return c.name == args[0] })
+	if i < 0 {
+		fmt.Fprintf(os.Stderr, "go-do-it: unknown command %q\n\n", args[0])
+		printCommandUsage(os.Stderr)
+		return 2
+	}
+	cmd := cliCommands[i]
+
+	if cmd.readOnly {
+		ls := *lists
+		ls.lock.onLocked = lockReadOnly
+		lists = &ls
+	}
+	store, err := lists.open(list)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "go-do-it:", err)
+		return 1
+	}
+	defer store.Close()
+	c := &cli{store: store, out: os.Stdout, errOut: os.Stderr}
+	return c.run(cmd, args[1:])
+}
+
+// run loads the list and runs cmd with args, returning the exit code.
+func (c *cli) run(cmd cliCommand, args []string) int {
+	todos, err := c.store.Load()
+	var skipped *skippedLinesError
+	if errors.As(err, &skipped) {
+		fmt.Fprintln(c.errOut, "go-do-it: warning:", err)
+	} else if err != nil {
+		fmt.Fprintln(c.errOut, "go-do-it:", err)
+		return 1
+	}
+	c.todos = todos
+
+	err = cmd.run(c, args)
+	var usage usageError
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.As(err, &usage):
+		fmt.Fprintf(c.errOut, "go-do-it %s: %v\nusage: go-do-it %s %s\n", cmd.name, err, cmd.name, cmd.args)
+		return 2
+	case err != nil:
+		fmt.Fprintf(c.errOut, "go-do-it %s: %v\n", cmd.name, err)
+		return 1
+	}
+	return 0
+}
+
+func printCommandUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: go-do-it [global flags] [command [flags] [args]]")
+	fmt.Fprintln(w, "\nWith no command the interactive todo list opens. Commands:")
+	for _, c := range cliCommands {
+		fmt.Fprintf(w, "  %-7s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w, "\nRun go-do-it COMMAND -h for a command's flags, and go-do-it -h for the global flags.")
+}
+
+// flags returns a flag set for the named command with the --json flag
+// every command shares.
+func (c *cli) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("go-do-it "+name, flag.ContinueOnError)
+	fs.SetOutput(c.errOut)
+	fs.BoolVar(&c.json, "json", false, "print JSON instead of text")
+	return fs
+}
+
+// parse parses flags that may come before, between or after the
+// positional arguments, and returns the positional arguments.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, usageError{err.Error()}
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return rest, nil
+		}
+		if args[0] == "--" {
+			return append(rest, args[1:]...), nil
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+}
+
+// isSet reports whether the named flag was given on the command line.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// find returns the index of the todo an ID or unique ID prefix refers to.
+func (c *cli) find(ref string) (int, error) {
+	if i := slices.IndexFunc(c.todos, func(t Todo) bool { return strings.EqualFold(t.ID, ref) }); i >= 0 {
+		return i, nil
+	}
+	found := -1
+	for i, t := range c.todos {
+		if ref != "" && strings.HasPrefix(strings.ToUpper(t.ID), strings.ToUpper(ref)) {
+			if found >= 0 {
+				return -1, fmt.Errorf("%s matches more than one todo; give more of the ID", ref)
+			}
+			found = i
+		}
+	}
+	if found < 0 {
+		return -1, fmt.Errorf("no todo with ID %s", ref)
+	}
+	return found, nil
+}
+
+// printTodos writes todos as JSON, or one line each.
+func (c *cli) printTodos(todos []Todo) error {
+	if c.json {
+		return c.printJSON(todos)
+	}
+	for _, t := range todos {
+		fmt.Fprintln(c.out, todoLine(t, 0))
+	}
+	return nil
+}
+
+func (c *cli) printJSON(v any) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// todoLine formats a todo for text output, e.g.
+// "01J9Z... [ ] Pay rent  due 2024-06-01  urgent  #home".
+func todoLine(t Todo, depth int) string {
+	check := "[ ]"
+	if t.Done {
+		check = "[x]"
+	}
+	line := fmt.Sprintf("%s %s %s%s", t.ID, check, strings.Repeat("  ", depth), t.Text)
+	if t.DueDate != "" {
+		line += "  due " + t.DueDate
+	}
+	line += "  " + t.Priority
+	for _, tag := range t.Tags {
+		line += "  #" + tag
+	}
+	if t.Recurrence != "" {
+		if r, err := parseRecurrence(t.Recurrence); err == nil {
+			line += "  (" + r.describe() + ")"
+		}
+	}
+	return line
+}
+
+// parseDue reads a due date given on the command line: YYYY-MM-DD or any
+// date phrase quick add understands, such as "tomorrow" or "in 3 days".
+func parseDue(s string, now time.Time) (string, error) {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return "", nil
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if d, n := parseDuePhrase(words, today); n == len(words) {
+		return d.Format(dateLayout), nil
+	}
+	return "", fmt.Errorf("bad due date %q (want YYYY-MM-DD, tomorrow, next friday, in 3 days, ...)", s)
+}
+
+func parsePriority(s string) (string, error) {
+	if p, ok := priorityWords[strings.ToLower(strings.TrimPrefix(s, "!"))]; ok {
+		return p, nil
+	}
+	return "", fmt.Errorf("bad priority %q (want urgent, medium or low)", s)
+}
+
+// splitTags reads a comma or space separated list of tags.
+func splitTags(s string) []string {
+	var tags []string
+	for _, w := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }) {
+		if w = strings.TrimLeft(w, "#+"); w != "" {
+			tags = append(tags, w)
+		}
+	}
+	return tags
+}
+
+func cmdAdd(c *cli, args []string) error {
+	fs := c.flags("add")
+	due := fs.String("due", "", "due date (YYYY-MM-DD, tomorrow, next friday, ...)")
+	priority := fs.String("priority", "", "urgent, medium or low")
+	tags := fs.String("tags", "", "comma separated tags, added to any #tags in TEXT")
+	repeat := fs.String("repeat", "", "how often it repeats, e.g. \"every monday\" or an RRULE")
+	parent := fs.String("parent", "", "ID of the todo to add this under")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	q := parseQuickAdd(strings.Join(args, " "), time.Now())
+	if q.text == "" {
+		return usageError{"no todo text given"}
+	}
+
+	t := newTodo(q.text)
+	t.DueDate, t.Priority, t.Tags, t.Recurrence = q.due, q.priority, q.tags, q.recurrence
+	if isSet(fs, "due") {
+		if t.DueDate, err = parseDue(*due, time.Now()); err != nil {
+			return usageError{err.Error()}
+		}
+	}
+	if isSet(fs, "priority") {
+		if t.Priority, err = parsePriority(*priority); err != nil {
+			return usageError{err.Error()}
+		}
+	}
+	t.Tags = append(t.Tags, splitTags(*tags)...)
+	if isSet(fs, "repeat") {
+		if t.Recurrence, err = recurrenceInput(*repeat); err != nil {
+			return usageError{err.Error()}
+		}
+	}
+	if *parent != "" {
+		i, err := c.find(*parent)
+		if err != nil {
+			return err
+		}
+		t.ParentID = c.todos[i].ID
+	}
+	if err := c.store.Put(t); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(t)
+	}
+	fmt.Fprintln(c.out, t.ID)
+	return nil
+}
+
+func cmdList(c *cli, args []string) error {
+	fs := c.flags("list")
+	all := fs.Bool("all", false, "include done todos")
+	done := fs.Bool("done", false, "only done todos")
+	tags := fs.String("tag", "", "only todos with these tags (comma separated)")
+	anyTag := fs.Bool("any", false, "with --tag, todos with any of the tags rather than all")
+	search := fs.String("search", "", "only todos whose text or tags fuzzy-match this, or whose notes contain it")
+	dueBefore := fs.String("due-before", "", "only todos due on or before this date")
+	overdue := fs.Bool("overdue", false, "only open todos past their due date")
+	sortBy := fs.String("sort", "none", "sort by "+strings.Join(sortKeyNames, ", "))
+	desc := fs.Bool("desc", false, "reverse the sort order")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageError{"unexpected argument " + args[0]}
+	}
+	if !slices.Contains(sortKeyNames, *sortBy) {
+		return usageError{fmt.Sprintf("unknown sort %q", *sortBy)}
+	}
+	before := ""
+	if *dueBefore != "" {
+		if before, err = parseDue(*dueBefore, time.Now()); err != nil {
+			return usageError{err.Error()}
+		}
+	}
+	today := time.Now().Format(dateLayout)
+	filter := parseTagFilter(*tags, *anyTag)
+
+	keep := func(t Todo) bool {
+		switch {
+		case *done && !t.Done, !*done && !*all && t.Done:
+			return false
+		case !filter.matches(t):
+			return false
+		case before != "" && (t.DueDate == "" || t.DueDate > before):
+			return false
+		case *overdue && (t.Done || t.DueDate == "" || t.DueDate >= today):
+			return false
+		}
+		if *search != "" {
+			if _, ok := searchTodo(t, *search); !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Subtasks are listed under their parents, indented, as in the TUI.
+	rows, depths := buildTree(c.todos, parseSortKey(*sortBy), *desc, nil)
+	var out []Todo
+	for r, i := range rows {
+		if !keep(c.todos[i]) {
+			continue
+		}
+		if c.json {
+			out = append(out, c.todos[i])
+			continue
+		}
+		fmt.Fprintln(c.out, todoLine(c.todos[i], depths[r]))
+	}
+	if c.json {
+		if out == nil {
+			out = []Todo{}
+		}
+		return c.printJSON(out)
+	}
+	return nil
+}
+
+func cmdDone(c *cli, args []string) error {
+	fs := c.flags("done")
+	undo := fs.Bool("undo", false, "mark the todos not done instead")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return usageError{"no todo ID given"}
+	}
+	// Every ID is checked before anything changes.
+	var found []int
+	for _, ref := range args {
+		i, err := c.find(ref)
+		if err != nil {
+			return err
+		}
+		found = append(found, i)
+	}
+	var changed []Todo
+	for _, i := range found {
+		t := c.todos[i]
+		t.setDone(!*undo)
+		// As in the TUI, ticking a repeating todo adds the next one.
+		next, repeats := nextOccurrence(t, time.Now())
+		if t.Done && repeats {
+			t.Recurrence = ""
+		}
+		if err := c.store.Put(t); err != nil {
+			return err
+		}
+		c.todos[i] = t
+		changed = append(changed, t)
+		if t.Done && repeats {
+			if err := c.store.Put(next); err != nil {
+				return err
+			}
+			c.todos = append(c.todos, next)
+			changed = append(changed, next)
+		}
+	}
+	return c.printTodos(changed)
+}
+
+func cmdEdit(c *cli, args []string) error {
+	fs := c.flags("edit")
+	text := fs.String("text", "", "new text")
+	due := fs.String("due", "", "new due date; empty to clear")
+	priority := fs.String("priority", "", "urgent, medium or low")
+	tags := fs.String("tags", "", "replace the tags (comma separated); empty to clear")
+	addTags := fs.String("add-tag", "", "tags to add (comma separated)")
+	rmTags := fs.String("rm-tag", "", "tags to remove (comma separated)")
+	repeat := fs.String("repeat", "", "how often it repeats; empty to stop repeating")
+	parent := fs.String("parent", "", "ID of the todo to move this under; empty for the top level")
+	notes := fs.String("notes", "", "new notes; empty to clear")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usageError{"want exactly one todo ID"}
+	}
+	i, err := c.find(args[0])
+	if err != nil {
+		return err
+	}
+	t := c.todos[i]
+
+	if isSet(fs, "text") {
+		if t.Text = strings.TrimSpace(*text); t.Text == "" {
+			return usageError{"todo text cannot be empty"}
+		}
+	}
+	if isSet(fs, "due") {
+		if t.DueDate, err = parseDue(*due, time.Now()); err != nil {
+			return usageError{err.Error()}
+		}
+	}
+	if isSet(fs, "priority") {
+		if t.Priority, err = parsePriority(*priority); err != nil {
+			return usageError{err.Error()}
+		}
+	}
+	if isSet(fs, "tags") {
+		t.Tags = splitTags(*tags)
+	}
+	for _, tag := range splitTags(*addTags) {
+		if !hasTag(t, tag) {
+			t.Tags = append(t.Tags, tag)
+		}
+	}
+	for _, tag := range splitTags(*rmTags) {
+		t.Tags = slices.DeleteFunc(t.Tags, func(have string) bool { return strings.EqualFold(have, tag) })
+	}
+	if isSet(fs, "repeat") {
+		if t.Recurrence, err = recurrenceInput(*repeat); err != nil {
+			return usageError{err.Error()}
+		}
+	}
+	if isSet(fs, "notes") {
+		t.Notes = strings.TrimRight(*notes, " \t\n")
+	}
+	if isSet(fs, "parent") {
+		t.ParentID = ""
+		if *parent != "" {
+			p, err := c.find(*parent)
+			if err != nil {
+				return err
+			}
+			if slices.Contains(subtree(c.todos, t.ID), p) {
+				return fmt.Errorf("cannot move a todo under itself or its own subtask")
+			}
+			t.ParentID = c.todos[p].ID
+		}
+	}
+	t.UpdatedAt = time.Now()
+	if err := c.store.Put(t); err != nil {
+		return err
+	}
+	return c.printTodos([]Todo{t})
+}
+
+func cmdRm(c *cli, args []string) error {
+	fs := c.flags("rm")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return usageError{"no todo ID given"}
+	}
+	doomed := make(map[int]bool)
+	for _, ref := range args {
+		i, err := c.find(ref)
+		if err != nil {
+			return err
+		}
+		for _, j := range subtree(c.todos, c.todos[i].ID) {
+			doomed[j] = true
+		}
+	}
+	var removed, kept []Todo
+	for i, t := range c.todos {
+		if doomed[i] {
+			removed = append(removed, t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+	if len(removed) == 1 {
+		err = c.store.Delete(removed[0].ID)
+	} else {
+		err = c.store.Save(kept)
+	}
+	if err != nil {
+		return err
+	}
+	return c.printTodos(removed)
+}
+
+func cmdTags(c *cli, args []string) error {
+	fs := c.flags("tags")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageError{"unexpected argument " + args[0]}
+	}
+	type tagCount struct {
+		Tag   string
+		Count int
+	}
+	counts := []tagCount{}
+	for _, tag := range allTags(c.todos) {
+		n := 0
+		for _, t := range c.todos {
+			if hasTag(t, tag) {
+				n++
+			}
+		}
+		counts = append(counts, tagCount{tag, n})
+	}
+	if c.json {
+		return c.printJSON(counts)
+	}
+	for _, tc := range counts {
+		fmt.Fprintf(c.out, "%-20s %d\n", tc.Tag, tc.Count)
+	}
+	return nil
+}
+
+func cmdImport(c *cli, args []string) error {
+	fs := c.flags("import")
+	formatName := fs.String("format", "", "file format: "+formatNames()+" (default: from the file extension)")
+	fs.StringVar(formatName, "from", "", "same as --format")
+	replace := fs.Bool("replace", false, "replace the whole list instead of adding to it")
+	dryRun := fs.Bool("dry-run", false, "only report what would be imported and which rows would be rejected")
+	columns := fs.String("map", "", "csv: map headers to fields, e.g. \"Task=text,Due Date=due\" (fields: "+strings.Join(csvColumns, ", ")+")")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usageError{"want exactly one file to import"}
+	}
+	f, err := findFormat(*formatName, args[0])
+	if err != nil {
+		return usageError{err.Error()}
+	}
+	var opts importOptions
+	if opts.columns, err = parseColumnMap(*columns); err != nil {
+		return usageError{err.Error()}
+	}
+	in := os.Stdin
+	if args[0] != "-" {
+		if in, err = os.Open(args[0]); err != nil {
+			return err
+		}
+		defer in.Close()
+	}
+	imported, rejected, err := f.read(in, opts)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", args[0], err)
+	}
+	existing := c.todos
+	if *replace {
+		existing = nil
+	}
+	checkImportedIDs(imported, existing)
+	backfillTodos(imported)
+
+	// Rejected rows are reported on stderr, or as the report itself on a
+	// dry run, and the rest are imported.
+	report := c.errOut
+	if *dryRun && !c.json {
+		report = c.out
+	}
+	for _, l := range rejected {
+		fmt.Fprintf(report, "%s:%d: rejected: %v\n", args[0], l.line, l.err)
+	}
+
+	// Todos already in the list, going by ID, are updated in place.
+	todos, added := imported, len(imported)
+	if !*replace {
+		todos = c.todos
+		added = 0
+		for _, t := range imported {
+			if indexOfTodo(todos, t.ID) < 0 {
+				added++
+			}
+			todos = putTodo(todos, t)
+		}
+	}
+	verb := "Imported"
+	if *dryRun {
+		verb = "Would import"
+	} else if err := c.store.Save(todos); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(imported)
+	}
+	fmt.Fprintf(c.out, "%s %d todo(s) from %s: %d new, %d updated", verb, len(imported), args[0], added, len(imported)-added)
+	if len(rejected) > 0 {
+		fmt.Fprintf(c.out, "; %d row(s) rejected", len(rejected))
+	}
+	fmt.Fprintln(c.out, ".")
+	return nil
+}
+
+func cmdExport(c *cli, args []string) error {
+	fs := c.flags("export")
+	formatName := fs.String("format", "", "file format: "+formatNames()+" (default: from the -o extension, else todotxt)")
+	fs.StringVar(formatName, "to", "", "same as --format")
+	output := fs.String("o", "", "file to write (default: stdout)")
+	var opts exportOptions
+	fs.StringVar(&opts.group, "group", "none", "markdown: group todos under headings by "+strings.Join(markdownGroups, ", "))
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageError{"unexpected argument " + args[0]}
+	}
+	if !slices.Contains(markdownGroups, opts.group) {
+		return usageError{fmt.Sprintf("unknown group %q", opts.group)}
+	}
+	if *formatName == "" && *output == "" {
+		*formatName = formats[0].name
+	}
+	f, err := findFormat(*formatName, *output)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+	if *output == "" {
+		return f.write(c.out, c.todos, opts)
+	}
+	out, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := f.write(out, c.todos, opts); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
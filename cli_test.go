@@ -0,0 +1,471 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runCLI runs a command line against store and returns its exit code and
+// what it wrote to stdout and stderr.
+func runCLI(t *testing.T, store Store, args ...string) (int, string, string) {
+	t.Helper()
+	i := slices.IndexFunc(cliCommands, func(c cliCommand) bool { return c.name == args[0] })
+	if i < 0 {
+		t.Fatalf("no command %q", args[0])
+	}
+	var out, errOut strings.Builder
+	c := &cli{store: store, out: &out, errOut: &errOut}
+	code := c.run(cliCommands[i], args[1:])
+	return code, out.String(), errOut.String()
+}
+
+// storedSummaries returns the summaries of what store holds.
+func storedSummaries(t *testing.T, store Store) []string {
+	t.Helper()
+	todos, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return summaries(todos)
+}
+
+func TestCLIFind(t *testing.T) {
+	c := &cli{todos: append(sampleTodos(), Todo{ID: "01JA00000000000000000000A"})}
+	tests := []struct {
+		ref  string
+		want int // -1 for an error
+	}{
+		{"01JA00000000000000000000A2", 1},
+		{"01ja00000000000000000000a3", 2},
+		{"01JA00000000000000000000A", 4}, // a whole ID wins over a prefix
+		{"01JA0000000000000000000", -1},  // more than one
+		{"01JB", -1},
+		{"", -1},
+	}
+	for _, tt := range tests {
+		got, err := c.find(tt.ref)
+		if got != tt.want || (err != nil) != (tt.want < 0) {
+			t.Errorf("find(%q) = %d, %v; want %d", tt.ref, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseDue(t *testing.T) {
+	// A Saturday.
+	now := time.Date(2026, 10, 17, 15, 4, 5, 0, time.Local)
+	tests := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"2026-12-24", "2026-12-24", true},
+		{"tomorrow", "2026-10-18", true},
+		{"next friday", "2026-10-23", true},
+		{"in 3 days", "2026-10-20", true},
+		{"", "", true},
+		{"someday", "", false},
+		{"tomorrow please", "", false},
+		{"2026-13-01", "", false},
+	}
+	for _, tt := range tests {
+		got, err := parseDue(tt.in, now)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("parseDue(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"urgent", "urgent", true},
+		{"High", "urgent", true},
+		{"!low", "low", true},
+		{"2", "medium", true},
+		{"", "", false},
+		{"highest", "", false},
+	}
+	for _, tt := range tests {
+		got, err := parsePriority(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("parsePriority(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestSplitTags(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"home", []string{"home"}},
+		{"home,work", []string{"home", "work"}},
+		{" #home, +work  errands ,", []string{"home", "work", "errands"}},
+		{"#,+", nil},
+	}
+	for _, tt := range tests {
+		if got := splitTags(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("splitTags(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCLIAdd(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string // summary of the new todo, without its ID
+	}{
+		{[]string{"Buy milk"}, `"Buy milk" medium due= tags=[] parent= rec=`},
+		{[]string{"Pay", "rent", "2026-12-01", "!urgent", "#home"}, `"Pay rent" urgent due=2026-12-01 tags=[home] parent= rec=`},
+		{[]string{"--due", "2026-12-24", "--priority", "low", "--tags", "xmas,family", "Wrap", "presents", "#gifts"},
+			`"Wrap presents" low due=2026-12-24 tags=[gifts xmas family] parent= rec=`},
+		{[]string{"Bins", "--repeat", "every 2 weeks"}, `"Bins" medium due= tags=[] parent= rec=FREQ=WEEKLY;INTERVAL=2`},
+		{[]string{"Find", "the", "bank", "details", "--parent", "01JA00000000000000000000A1"},
+			`"Find the bank details" medium due= tags=[] parent=01JA00000000000000000000A1 rec=`},
+		{[]string{"--", "--not", "a", "flag"}, `"--not a flag" medium due= tags=[] parent= rec=`},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			store := newMemoryStore(sampleTodos()...)
+			code, out, errOut := runCLI(t, store, append([]string{"add"}, tt.args...)...)
+			if code != 0 {
+				t.Fatalf("exit %d: %s", code, errOut)
+			}
+			todos, _ := store.Load()
+			if len(todos) != 5 {
+				t.Fatalf("got %d todos, want 5", len(todos))
+			}
+			added := todos[4]
+			if out != added.ID+"\n" {
+				t.Errorf("printed %q, want the new ID %s", out, added.ID)
+			}
+			if got := strings.TrimPrefix(summary(added), added.ID+" [ ] "); got != tt.want {
+				t.Errorf("added %s\nwant %s", got, tt.want)
+			}
+			if !slices.Equal(summaries(todos[:4]), summaries(sampleTodos())) {
+				t.Errorf("other todos changed: %v", summaries(todos[:4]))
+			}
+		})
+	}
+}
+
+func TestCLIAddJSON(t *testing.T) {
+	store := newMemoryStore()
+	code, out, errOut := runCLI(t, store, "add", "--json", "Buy milk #shop")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	var got Todo
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("%v in %s", err, out)
+	}
+	todos, _ := store.Load()
+	if len(todos) != 1 || summary(got) != summary(todos[0]) || !got.CreatedAt.Equal(todos[0].CreatedAt) {
+		t.Errorf("printed %s, stored %v", summary(got), summaries(todos))
+	}
+}
+
+func TestCLIList(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string // IDs of the todos listed, without the common prefix
+	}{
+		{nil, []string{"A1", "  A2", "A4"}},
+		{[]string{"--all"}, []string{"A1", "  A2", "A3", "A4"}},
+		{[]string{"--done"}, []string{"A3"}},
+		{[]string{"--tag", "phone"}, []string{"  A2"}},
+		{[]string{"--tag", "home,phone"}, []string{"  A2"}},
+		{[]string{"--tag", "home,phone", "--any"}, []string{"A1", "  A2"}},
+		{[]string{"--search", "bank"}, []string{"  A2"}},
+		{[]string{"--due-before", "2026-10-31"}, []string{"A4"}},
+		{[]string{"--sort", "alpha"}, []string{"A1", "  A2", "A4"}},
+		{[]string{"--sort", "due", "--desc"}, []string{"A1", "  A2", "A4"}},
+		{[]string{"--sort", "due"}, []string{"A4", "A1", "  A2"}},
+		{[]string{"--tag", "nothing"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			code, out, errOut := runCLI(t, newMemoryStore(sampleTodos()...), append([]string{"list"}, tt.args...)...)
+			if code != 0 {
+				t.Fatalf("exit %d: %s", code, errOut)
+			}
+			var got []string
+			for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+				if id, rest, ok := strings.Cut(line, " [ ] "); ok || strings.Contains(line, " [x] ") {
+					if !ok {
+						id, rest, _ = strings.Cut(line, " [x] ")
+					}
+					indent := rest[:len(rest)-len(strings.TrimLeft(rest, " "))]
+					got = append(got, indent+strings.TrimPrefix(id, "01JA000000000000000000"+"00"))
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("listed %q, want %q\n%s", got, tt.want, out)
+			}
+		})
+	}
+}
+
+func TestCLIListText(t *testing.T) {
+	_, out, _ := runCLI(t, newMemoryStore(sampleTodos()...), "list", "--all")
+	want := `01JA00000000000000000000A1 [ ] Pay rent  due 2026-11-01  urgent  #home  (every month)
+01JA00000000000000000000A2 [ ]   Call the bank  medium  #home  #phone
+01JA00000000000000000000A3 [x] File taxes  low
+01JA00000000000000000000A4 [ ] Water plants  due 2026-10-20  medium  (every week on Mon, Thu)
+`
+	if out != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestCLIListJSON(t *testing.T) {
+	for _, args := range [][]string{{"list", "--json", "--all"}, {"list", "--json", "--tag", "nothing"}} {
+		_, out, _ := runCLI(t, newMemoryStore(sampleTodos()...), args...)
+		var got []Todo
+		if err := json.Unmarshal([]byte(out), &got); err != nil || got == nil {
+			t.Fatalf("%v: %v in %s", args, err, out)
+		}
+		want := sampleTodos()
+		if len(args) > 3 {
+			want = []Todo{}
+		}
+		if !slices.Equal(summaries(got), summaries(want)) {
+			t.Errorf("%v: got %v", args, summaries(got))
+		}
+	}
+}
+
+func TestCLIDone(t *testing.T) {
+	store := newMemoryStore(sampleTodos()...)
+	code, out, errOut := runCLI(t, store, "done", "01JA00000000000000000000A2", "01ja00000000000000000000a4")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	todos, _ := store.Load()
+	if len(todos) != 5 {
+		t.Fatalf("got %v, want the repeating todo's next occurrence added", summaries(todos))
+	}
+	if !todos[1].Done || todos[1].CompletedAt.IsZero() {
+		t.Errorf("subtask not done: %s", summary(todos[1]))
+	}
+	// The repeat moves on to the next occurrence.
+	if !todos[3].Done || todos[3].Recurrence != "" {
+		t.Errorf("repeating todo: %s", summary(todos[3]))
+	}
+	if next := todos[4]; next.Done || next.Text != "Water plants" || next.Recurrence != "FREQ=WEEKLY;BYDAY=MO,TH" || next.DueDate <= "2026-10-20" {
+		t.Errorf("next occurrence: %s", summary(next))
+	}
+	if n := strings.Count(out, "\n"); n != 3 {
+		t.Errorf("printed %d lines, want 3:\n%s", n, out)
+	}
+
+	code, _, errOut = runCLI(t, store, "done", "--undo", "01JA00000000000000000000A3")
+	if todos, _ := store.Load(); code != 0 || todos[2].Done || !todos[2].CompletedAt.IsZero() {
+		t.Errorf("undo: exit %d, %s: %s", code, errOut, summary(todos[2]))
+	}
+}
+
+func TestCLIEdit(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string // summary of A2 afterwards
+	}{
+		{[]string{"--text", " Call the bank back "}, `"Call the bank back" medium due= tags=[home phone] parent=01JA00000000000000000000A1 rec=`},
+		{[]string{"--due", "2026-11-05", "--priority", "urgent"}, `"Call the bank" urgent due=2026-11-05 tags=[home phone] parent=01JA00000000000000000000A1 rec=`},
+		{[]string{"--tags", "work"}, `"Call the bank" medium due= tags=[work] parent=01JA00000000000000000000A1 rec=`},
+		{[]string{"--tags", ""}, `"Call the bank" medium due= tags=[] parent=01JA00000000000000000000A1 rec=`},
+		{[]string{"--add-tag", "HOME,money", "--rm-tag", "Phone"}, `"Call the bank" medium due= tags=[home money] parent=01JA00000000000000000000A1 rec=`},
+		{[]string{"--repeat", "monthly"}, `"Call the bank" medium due= tags=[home phone] parent=01JA00000000000000000000A1 rec=FREQ=MONTHLY`},
+		{[]string{"--parent", ""}, `"Call the bank" medium due= tags=[home phone] parent= rec=`},
+		{[]string{"--parent", "01JA00000000000000000000A4"}, `"Call the bank" medium due= tags=[home phone] parent=01JA00000000000000000000A4 rec=`},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			store := newMemoryStore(sampleTodos()...)
+			code, out, errOut := runCLI(t, store, append([]string{"edit", "01JA00000000000000000000A2"}, tt.args...)...)
+			if code != 0 {
+				t.Fatalf("exit %d: %s", code, errOut)
+			}
+			todos, _ := store.Load()
+			if got := strings.TrimPrefix(summary(todos[1]), "01JA00000000000000000000A2 [ ] "); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+			if !todos[1].UpdatedAt.After(sampleTodos()[1].UpdatedAt) {
+				t.Error("updated time not set")
+			}
+			if !strings.HasPrefix(out, "01JA00000000000000000000A2 ") {
+				t.Errorf("printed %q", out)
+			}
+		})
+	}
+}
+
+func TestCLIEditNotes(t *testing.T) {
+	store := newMemoryStore(sampleTodos()...)
+	if code, _, errOut := runCLI(t, store, "edit", "01JA00000000000000000000A3", "--notes", "Receipt in the drawer\n\n"); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	if todos, _ := store.Load(); todos[2].Notes != "Receipt in the drawer" {
+		t.Errorf("notes %q", todos[2].Notes)
+	}
+}
+
+func TestCLIRm(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string // IDs left, without the common prefix
+	}{
+		{[]string{"01JA00000000000000000000A3"}, []string{"A1", "A2", "A4"}},
+		{[]string{"01JA00000000000000000000A1"}, []string{"A3", "A4"}}, // with its subtask
+		{[]string{"01JA00000000000000000000A2", "01JA00000000000000000000A4"}, []string{"A1", "A3"}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			store := newMemoryStore(sampleTodos()...)
+			code, out, errOut := runCLI(t, store, append([]string{"rm"}, tt.args...)...)
+			if code != 0 {
+				t.Fatalf("exit %d: %s", code, errOut)
+			}
+			todos, _ := store.Load()
+			var got []string
+			for _, td := range todos {
+				got = append(got, strings.TrimPrefix(td.ID, "01JA000000000000000000"+"00"))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("left %v, want %v", got, tt.want)
+			}
+			if n := strings.Count(out, "\n"); n != 4-len(tt.want) {
+				t.Errorf("printed %d removed todos, want %d:\n%s", n, 4-len(tt.want), out)
+			}
+		})
+	}
+}
+
+func TestCLITags(t *testing.T) {
+	_, out, _ := runCLI(t, newMemoryStore(sampleTodos()...), "tags")
+	if want := "home                 2\nphone                1\n"; out != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+	_, out, _ = runCLI(t, newMemoryStore(sampleTodos()...), "tags", "--json")
+	var got []struct {
+		Tag   string
+		Count int
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil || len(got) != 2 || got[0].Tag != "home" || got[0].Count != 2 {
+		t.Errorf("got %+v, %v from %s", got, err, out)
+	}
+}
+
+func TestCLIImportExport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todos.csv")
+	code, _, errOut := runCLI(t, newMemoryStore(sampleTodos()...), "export", "-o", path)
+	if code != 0 {
+		t.Fatalf("export: exit %d: %s", code, errOut)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, append(b, "Bad,,,highest\n"...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := newMemoryStore(sampleTodos()[:1]...)
+	code, out, errOut := runCLI(t, store, "import", "--dry-run", path)
+	if code != 0 || !strings.Contains(out, "Would import 4 todo(s) from "+path+": 3 new, 1 updated; 1 row(s) rejected.") ||
+		!strings.Contains(out, path+":6: rejected") {
+		t.Errorf("dry run: exit %d\n%s%s", code, out, errOut)
+	}
+	if got := storedSummaries(t, store); len(got) != 1 {
+		t.Errorf("dry run changed the list: %v", got)
+	}
+
+	code, _, errOut = runCLI(t, store, "import", path)
+	if code != 0 || !strings.Contains(errOut, ":6: rejected") {
+		t.Errorf("import: exit %d\n%s", code, errOut)
+	}
+	if got, want := storedSummaries(t, store), summaries(sampleTodos()); !slices.Equal(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCLIImportRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todos.ics")
+	ics := "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nSUMMARY:Pay rent\r\nEND:VTODO\r\n" +
+		"BEGIN:VTODO\r\nSUMMARY:Bad due\r\nDUE:soon\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+	if err := os.WriteFile(path, []byte(ics), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store := newMemoryStore()
+	code, out, errOut := runCLI(t, store, "import", path)
+	if code != 0 || !strings.Contains(out, "1 todo(s)") || !strings.Contains(out, "1 row(s) rejected") ||
+		!strings.Contains(errOut, path+":5: rejected") {
+		t.Errorf("exit %d\n%s%s", code, out, errOut)
+	}
+	if todos, _ := store.Load(); len(todos) != 1 || todos[0].Text != "Pay rent" {
+		t.Errorf("stored %v", summaries(todos))
+	}
+}
+
+func TestCLIErrors(t *testing.T) {
+	tests := []struct {
+		args   []string
+		code   int
+		errOut string
+	}{
+		{[]string{"add"}, 2, "no todo text given"},
+		{[]string{"add", "--priority", "highest", "x"}, 2, `bad priority "highest"`},
+		{[]string{"add", "--due", "someday", "x"}, 2, `bad due date "someday"`},
+		{[]string{"add", "--repeat", "now and then", "x"}, 2, "now and then"},
+		{[]string{"add", "--parent", "01JB", "x"}, 1, "no todo with ID 01JB"},
+		{[]string{"add", "--bogus", "x"}, 2, "flag provided but not defined: -bogus"},
+		{[]string{"list", "extra"}, 2, "unexpected argument extra"},
+		{[]string{"list", "--sort", "size"}, 2, `unknown sort "size"`},
+		{[]string{"list", "--due-before", "whenever"}, 2, "bad due date"},
+		{[]string{"done"}, 2, "no todo ID given"},
+		{[]string{"done", "01JA"}, 1, "01JA matches more than one todo"},
+		{[]string{"done", "01JA00000000000000000000A1", "nope"}, 1, "no todo with ID nope"},
+		{[]string{"edit", "01JA00000000000000000000A1", "01JA00000000000000000000A2"}, 2, "want exactly one todo ID"},
+		{[]string{"edit", "01JA00000000000000000000A1", "--text", " "}, 2, "todo text cannot be empty"},
+		{[]string{"edit", "01JA00000000000000000000A1", "--priority", "soon"}, 2, `bad priority "soon"`},
+		{[]string{"edit", "01JA00000000000000000000A1", "--due", "2026-02-30"}, 2, "bad due date"},
+		{[]string{"edit", "01JA00000000000000000000A1", "--parent", "01JA00000000000000000000A2"}, 1, "cannot move a todo under itself"},
+		{[]string{"rm"}, 2, "no todo ID given"},
+		{[]string{"rm", "zzz"}, 1, "no todo with ID zzz"},
+		{[]string{"tags", "x"}, 2, "unexpected argument x"},
+		{[]string{"import"}, 2, "want exactly one file"},
+		{[]string{"import", "todos.unknown"}, 2, "todos.unknown"},
+		{[]string{"import", "--format", "csv", "no/such/file.csv"}, 1, "no/such/file.csv"},
+		{[]string{"export", "--group", "size"}, 2, `unknown group "size"`},
+		{[]string{"export", "--format", "pdf"}, 2, "pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			store := newMemoryStore(sampleTodos()...)
+			code, out, errOut := runCLI(t, store, tt.args...)
+			if code != tt.code || !strings.Contains(errOut, tt.errOut) {
+				t.Errorf("exit %d, stderr %q; want exit %d and %q", code, errOut, tt.code, tt.errOut)
+			}
+			if out != "" {
+				t.Errorf("printed %q", out)
+			}
+			if got := storedSummaries(t, store); !slices.Equal(got, summaries(sampleTodos())) {
+				t.Errorf("list changed: %v", got)
+			}
+		})
+	}
+}
+
+func TestCLIHelp(t *testing.T) {
+	code, _, errOut := runCLI(t, newMemoryStore(), "add", "-h")
+	if code != 0 || !strings.Contains(errOut, "-priority") {
+		t.Errorf("exit %d, stderr %q", code, errOut)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dataFile picks the todo file: the --file flag, then $GODOIT_FILE, then
+// todolist.txt under $XDG_DATA_HOME/go-do-it. The default location adopts
+// an existing ./todolist.txt the first time it is used.
+func dataFile(flagPath string) (string, error) {
+	path := flagPath
+	if path == "" {
+		path = os.Getenv("GODOIT_FILE")
+	}
+	if path == "" {
+		dir, err := dataDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(dir, todoFile)
+		for _, legacy := range []string{todoFile, sqliteFile} {
+			dst := filepath.Join(dir, legacy)
+			moved, err := migrateLegacyFile(legacy, dst)
+			if err != nil {
+				return "", fmt.Errorf("move %s to %s: %w", legacy, dst, err)
+			}
+			if moved {
+				fmt.Fprintf(os.Stderr, "Moved ./%s to %s\n", legacy, dst)
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// dataDir returns $XDG_DATA_HOME/go-do-it, falling back to
+// ~/.local/share/go-do-it as the XDG spec says.
+func dataDir() (string, error) {
+	base := os.Getenv("XDG_DATA_HOME")
+	if base == "" || !filepath.IsAbs(base) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(base, "go-do-it"), nil
+}
+
+// sqlitePath returns the database file that sits next to a todo file.
+func sqlitePath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".db"
+}
+
+// migrateLegacyFile moves src to dst unless dst already exists or there is
+// nothing at src. It reports whether a file was moved.
+func migrateLegacyFile(src, dst string) (bool, error) {
+	if _, err := os.Stat(dst); !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	if _, err := os.Stat(src); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return false, err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return true, nil
+	}
+	// Rename fails across file systems; copy and remove instead.
+	if err := copyFile(src, dst); err != nil {
+		return false, err
+	}
+	return true, os.Remove(src)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
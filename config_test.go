@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDataFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	t.Setenv("GODOIT_FILE", "")
+	xdg := filepath.Join(dir, "data", "go-do-it", todoFile)
+
+	got, err := dataFile("")
+	if err != nil || got != xdg {
+		t.Errorf("dataFile() = %q, %v; want %q", got, err, xdg)
+	}
+
+	t.Setenv("GODOIT_FILE", filepath.Join(dir, "env", "todo.txt"))
+	if got, _ := dataFile(""); got != filepath.Join(dir, "env", "todo.txt") {
+		t.Errorf("dataFile() with GODOIT_FILE = %q", got)
+	}
+	if got, _ := dataFile(filepath.Join(dir, "flag.txt")); got != filepath.Join(dir, "flag.txt") {
+		t.Errorf("dataFile(flag) = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "env")); err != nil {
+		t.Errorf("directory for GODOIT_FILE was not made: %v", err)
+	}
+
+	t.Setenv("XDG_DATA_HOME", "relative")
+	if d, _ := dataDir(); !filepath.IsAbs(d) {
+		t.Errorf("dataDir() with a relative XDG_DATA_HOME = %q", d)
+	}
+}
+
+func TestDataFileAdoptsLegacyFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	t.Setenv("GODOIT_FILE", "")
+	if err := os.WriteFile(todoFile, []byte("[ ] Pay rent\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := dataFile("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "[ ] Pay rent\n" {
+		t.Errorf("legacy file was not moved: %q, %v", data, err)
+	}
+	if _, err := os.Stat(todoFile); !os.IsNotExist(err) {
+		t.Errorf("legacy file is still in the working directory: %v", err)
+	}
+
+	// Once there is a file in the data directory, a new ./todolist.txt is
+	// left alone.
+	os.WriteFile(todoFile, []byte("[ ] Other\n"), 0o644)
+	if _, err := dataFile(""); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "[ ] Pay rent\n" {
+		t.Errorf("data file was overwritten with %q", data)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSV files have a header row naming the columns. Export writes every
+// Todo field under the names in csvColumns, with tags joined by commas
+// and dates and times in ISO 8601. Import finds columns by those names (or
+// a few common aliases such as "Title" or "Due Date"), or by a mapping
+// given with --map; columns it does not know are ignored. Rows with an
+// invalid priority, date or the like are rejected and reported, and the
+// rest are imported.
+//
+// Spreadsheets run a cell starting with =, +, - or @ as a formula, so such
+// cells are written with a leading ' that spreadsheets hide, and import
+// takes it off again.
+
+var csvColumns = []string{"id", "text", "done", "priority", "due", "tags", "parent", "recurrence", "created", "updated", "completed", "notes"}
+
+// csvAliases are other header names import recognises for a column.
+var csvAliases = map[string]string{
+	"uid": "id", "title": "text", "task": "text", "todo": "text", "summary": "text", "name": "text",
+	"completed?": "done", "status": "done", "due date": "due", "due_date": "due", "deadline": "due",
+	"tag": "tags", "labels": "tags", "categories": "tags", "parent id": "parent", "repeat": "recurrence",
+	"rrule": "recurrence", "created at": "created", "updated at": "updated", "completed at": "completed",
+	"description": "notes", "note": "notes", "comments": "notes",
+}
+
+func writeCSV(w io.Writer, todos []Todo, _ exportOptions) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvColumns)
+	for _, t := range todos {
+		row := []string{
+			t.ID,
+			t.Text,
+			strconv.FormatBool(t.Done),
+			t.Priority,
+			t.DueDate,
+			strings.Join(t.Tags, ", "),
+			t.ParentID,
+			t.Recurrence,
+			formatTime(t.CreatedAt),
+			formatTime(t.UpdatedAt),
+			formatTime(t.CompletedAt),
+			t.Notes,
+		}
+		for i := range row {
+			row[i] = csvEscape(row[i])
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvEscape puts a ' in front of a cell a spreadsheet would take for a
+// formula. Cells that already start with ' and then look like one get
+// another, so csvUnescape gives back exactly what was written.
+func csvEscape(s string) string {
+	if rest := strings.TrimLeft(s, "'"); rest != "" && strings.ContainsRune("=+-@\t\r", rune(rest[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// csvUnescape undoes csvEscape.
+func csvUnescape(s string) string {
+	if rest, ok := strings.CutPrefix(s, "'"); ok && csvEscape(rest) == s {
+		return rest
+	}
+	return s
+}
+
+// parseColumnMap reads a --map value such as "Task=text,Due Date=due",
+// mapping CSV headers to the columns in csvColumns.
+func parseColumnMap(s string) (map[string]string, error) {
+	columns := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		header, field, ok := strings.Cut(pair, "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !ok || strings.TrimSpace(header) == "" {
+			return nil, fmt.Errorf("bad column mapping %q (want HEADER=FIELD)", pair)
+		}
+		if !slices.Contains(csvColumns, field) {
+			return nil, fmt.Errorf("unknown field %q in mapping (want one of %s)", field, strings.Join(csvColumns, ", "))
+		}
+		columns[strings.ToLower(strings.TrimSpace(header))] = field
+	}
+	return columns, nil
+}
+
+func readCSV(r io.Reader, opts importOptions) ([]Todo, []badLine, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// field maps each column we know to its index in a row. Headers named
+	// in --map come first, then ones we recognise by name.
+	field := map[string]int{}
+	for pass := 0; pass < 2; pass++ {
+		for i, h := range header {
+			h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+			name, ok := opts.columns[h]
+			if pass == 1 {
+				if name, ok = csvAliases[h]; !ok && slices.Contains(csvColumns, h) {
+					name, ok = h, true
+				}
+			}
+			if _, dup := field[name]; ok && !dup {
+				field[name] = i
+			}
+		}
+	}
+	if _, ok := field["text"]; !ok {
+		return nil, nil, fmt.Errorf("no column for the todo text in header %q; name one with --map HEADER=text", strings.Join(header, ","))
+	}
+
+	var todos []Todo
+	var bad []badLine
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if pe, ok := err.(*csv.ParseError); ok {
+				bad = append(bad, badLine{line: pe.StartLine, text: strings.Join(row, ","), err: pe.Err})
+				continue
+			}
+			return nil, nil, err
+		}
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue // spreadsheets often leave empty rows at the end
+		}
+		line, _ := cr.FieldPos(0)
+		get := func(name string) string {
+			if i, ok := field[name]; ok && i < len(row) {
+				return csvUnescape(strings.TrimSpace(row[i]))
+			}
+			return ""
+		}
+		t, err := csvTodo(get)
+		if err != nil {
+			bad = append(bad, badLine{line: line, text: strings.Join(row, ","), err: err})
+			continue
+		}
+		todos = append(todos, t)
+	}
+	return todos, bad, nil
+}
+
+// csvTodo builds a todo from one row, checking each value.
+func csvTodo(get func(string) string) (Todo, error) {
+	t := Todo{ID: get("id"), Text: get("text"), ParentID: get("parent"), Notes: get("notes"), Priority: "medium"}
+	if t.Text == "" {
+		return t, fmt.Errorf("empty text")
+	}
+	var err error
+	if s := get("priority"); s != "" {
+		if t.Priority, err = parsePriority(s); err != nil {
+			return t, err
+		}
+	}
+	if s := get("done"); s != "" {
+		switch strings.ToLower(s) {
+		case "true", "yes", "y", "1", "x", "done", "completed":
+			t.Done = true
+		case "false", "no", "n", "0", "", "open", "todo", "needs-action":
+		default:
+			return t, fmt.Errorf("bad done value %q (want true or false)", s)
+		}
+	}
+	if s := get("due"); s != "" {
+		due, err := parseCSVTime(s)
+		if err != nil {
+			return t, fmt.Errorf("bad due date %q (want YYYY-MM-DD)", s)
+		}
+		t.DueDate = due.Format(dateLayout)
+	}
+	for _, tag := range strings.FieldsFunc(get("tags"), func(r rune) bool { return r == ',' || r == ';' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			t.Tags = append(t.Tags, tag)
+		}
+	}
+	if t.Recurrence, err = recurrenceInput(get("recurrence")); err != nil {
+		return t, err
+	}
+	for _, ts := range []struct {
+		name string
+		dst  *time.Time
+	}{{"created", &t.CreatedAt}, {"updated", &t.UpdatedAt}, {"completed", &t.CompletedAt}} {
+		if s := get(ts.name); s != "" {
+			if *ts.dst, err = parseCSVTime(s); err != nil {
+				return t, fmt.Errorf("bad %s time %q (want ISO 8601)", ts.name, s)
+			}
+		}
+	}
+	return t, nil
+}
+
+// parseCSVTime reads an ISO 8601 date, or a date and time as spreadsheets
+// tend to write them.
+func parseCSVTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{dateLayout, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("bad time %q", s)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	todos := sampleTodos()
+	todos[0].Text = `Pay "rent", then, file the receipt`
+	todos[1].UpdatedAt = todos[1].UpdatedAt.Add(90*time.Minute + 1500*time.Millisecond)
+	todos[1].Tags = []string{"@phone", "home"}
+	todos[2].Text = `=HYPERLINK("https://example.com","taxes")`
+	todos[3].Text = "'=not a formula"
+	out, got := roundTrip(t, "csv", todos, exportOptions{})
+	if !strings.Contains(out, `"'=HYPERLINK(`) || !strings.Contains(out, "'@phone") || !strings.Contains(out, "''=not") {
+		t.Errorf("formula cells not escaped:\n%s", out)
+	}
+	if !slices.Equal(summaries(got), summaries(todos)) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(summaries(got), "\n"), strings.Join(summaries(todos), "\n"))
+	}
+	for i, td := range got {
+		if !td.CreatedAt.Equal(todos[i].CreatedAt) || !td.UpdatedAt.Equal(todos[i].UpdatedAt) || !td.CompletedAt.Equal(todos[i].CompletedAt) {
+			t.Errorf("todo %d: times %v %v %v, want %v %v %v", i, td.CreatedAt, td.UpdatedAt, td.CompletedAt,
+				todos[i].CreatedAt, todos[i].UpdatedAt, todos[i].CompletedAt)
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		columns  string
+		want     []string // summaries
+		rejected []int    // line numbers
+	}{
+		{
+			name: "aliases",
+			input: "\ufeffTitle,Status,Due Date,Labels,Priority,Owner\n" +
+				"Write report,open,2026-11-01,\"work; q4\",High,sam\n" +
+				"Send invoice,Done,2026-10-20 17:00,,low,\n",
+			want: []string{
+				` [ ] "Write report" urgent due=2026-11-01 tags=[work q4] parent= rec=`,
+				` [x] "Send invoice" low due=2026-10-20 tags=[] parent= rec=`,
+			},
+		},
+		{
+			name:    "mapped columns win over names",
+			input:   "Summary Line,Deadline,text\nReal text,2026-11-02,ignored\n",
+			columns: "Summary Line=text,Deadline=due",
+			want:    []string{` [ ] "Real text" medium due=2026-11-02 tags=[] parent= rec=`},
+		},
+		{
+			name: "bad rows are rejected and the rest kept",
+			input: "text,priority,due,done,recurrence,created\n" +
+				"Fine,,,,,\n" +
+				"Bad priority,highest,,,,\n" +
+				"\n" +
+				"Bad due,,soon,,,\n" +
+				"Bad done,,,maybe,,\n" +
+				"Bad repeat,,,,fortnightly-ish,\n" +
+				"Bad created,,,,,yesterday\n" +
+				",,,,,\n" +
+				"Repeats,,,,every 2 weeks,\n",
+			want: []string{
+				` [ ] "Fine" medium due= tags=[] parent= rec=`,
+				` [ ] "Repeats" medium due= tags=[] parent= rec=FREQ=WEEKLY;INTERVAL=2`,
+			},
+			rejected: []int{3, 5, 6, 7, 8},
+		},
+		{
+			name:     "malformed quoting",
+			input:    "text\n\"unterminated\n",
+			rejected: []int{2},
+		},
+		{
+			name:  "header only",
+			input: "text,due\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := parseColumnMap(tt.columns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, bad, err := readCSV(strings.NewReader(tt.input), importOptions{columns: columns})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(summaries(got), tt.want) {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(summaries(got), "\n"), strings.Join(tt.want, "\n"))
+			}
+			var lines []int
+			for _, b := range bad {
+				lines = append(lines, b.line)
+			}
+			if !slices.Equal(lines, tt.rejected) {
+				t.Errorf("rejected lines %v, want %v", lines, tt.rejected)
+			}
+		})
+	}
+}
+
+func TestCSVEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Pay rent", "Pay rent"},
+		{"", ""},
+		{"=1+1", "'=1+1"},
+		{"+44 20 7946 0000", "'+44 20 7946 0000"},
+		{"-", "'-"},
+		{"@home", "'@home"},
+		{"\t=cmd", "'\t=cmd"},
+		{"'quoted'", "'quoted'"},
+		{"'=1+1", "''=1+1"},
+		{"Total =1+1", "Total =1+1"},
+	}
+	for _, tt := range tests {
+		if got := csvEscape(tt.in); got != tt.want {
+			t.Errorf("csvEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if got := csvUnescape(tt.want); got != tt.in {
+			t.Errorf("csvUnescape(%q) = %q, want %q", tt.want, got, tt.in)
+		}
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	for _, input := range []string{
+		"Owner,Due\nsam,2026-11-01\n",
+		"\"bad header\n",
+	} {
+		if todos, _, err := readCSV(strings.NewReader(input), importOptions{}); err == nil {
+			t.Errorf("readCSV(%q) = %v, want an error", input, todos)
+		}
+	}
+}
+
+func TestParseColumnMap(t *testing.T) {
+	got, err := parseColumnMap(" Task = text , Due Date=DUE,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["task"] != "text" || got["due date"] != "due" {
+		t.Errorf("got %v", got)
+	}
+	for _, s := range []string{"Task", "=text", "Task=title"} {
+		if _, err := parseColumnMap(s); err == nil {
+			t.Errorf("parseColumnMap(%q) succeeded, want an error", s)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// tagFilter narrows the list to todos carrying its tags: all of them, or
+// with any set, at least one. Tags match case-insensitively.
+type tagFilter struct {
+	tags []string
+	any  bool
+}
+
+// parseTagFilter reads tags separated by spaces or commas, with an optional
+// leading '#' on each.
+func parseTagFilter(input string, any bool) tagFilter {
+	f := tagFilter{any: any}
+	for _, w := range strings.FieldsFunc(input, func(r rune) bool { return r == ' ' || r == ',' }) {
+		if w = strings.TrimPrefix(w, "#"); w != "" {
+			f.tags = append(f.tags, w)
+		}
+	}
+	return f
+}
+
+func (f tagFilter) active() bool {
+	return len(f.tags) > 0
+}
+
+func (f tagFilter) matches(t Todo) bool {
+	if !f.active() {
+		return true
+	}
+	for _, want := range f.tags {
+		has := hasTag(t, want)
+		if has && f.any {
+			return true
+		}
+		if !has && !f.any {
+			return false
+		}
+	}
+	return !f.any
+}
+
+func (f tagFilter) String() string {
+	op := " AND "
+	if f.any {
+		op = " OR "
+	}
+	parts := make([]string, len(f.tags))
+	for i, tag := range f.tags {
+		parts[i] = "#" + tag
+	}
+	return strings.Join(parts, op)
+}
+
+func hasTag(t Todo, tag string) bool {
+	for _, have := range t.Tags {
+		if strings.EqualFold(have, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// allTags returns every tag used in todos, sorted.
+func allTags(todos []Todo) []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, t := range todos {
+		for _, tag := range t.Tags {
+			if key := strings.ToLower(tag); tag != "" && !seen[key] {
+				seen[key] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// tagSuggestions returns the tags containing the word being typed at the
+// end of input.
+func tagSuggestions(todos []Todo, input string) []string {
+	word := ""
+	if !strings.HasSuffix(input, " ") && !strings.HasSuffix(input, ",") {
+		if words := strings.Fields(strings.ReplaceAll(input, ",", " ")); len(words) > 0 {
+			word = strings.ToLower(strings.TrimPrefix(words[len(words)-1], "#"))
+		}
+	}
+	var out []string
+	for _, tag := range allTags(todos) {
+		if word == "" || strings.Contains(strings.ToLower(tag), word) {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTagFilter(t *testing.T) {
+	todos := []Todo{
+		{Text: "Pay rent", Tags: []string{"home", "money"}},
+		{Text: "Call the bank", Tags: []string{"Money", "phone"}},
+		{Text: "Water plants", Tags: []string{"home"}},
+		{Text: "Read"},
+	}
+	tests := []struct {
+		input string
+		any   bool
+		str   string
+		want  []string
+	}{
+		{"", false, "", []string{"Pay rent", "Call the bank", "Water plants", "Read"}},
+		{"home", false, "#home", []string{"Pay rent", "Water plants"}},
+		{"#MONEY", false, "#MONEY", []string{"Pay rent", "Call the bank"}},
+		{"home money", false, "#home AND #money", []string{"Pay rent"}},
+		{"#home, phone", true, "#home OR #phone", []string{"Pay rent", "Call the bank", "Water plants"}},
+		{"home,,# nothing", false, "#home AND #nothing", nil},
+	}
+	for _, tt := range tests {
+		f := parseTagFilter(tt.input, tt.any)
+		if f.String() != tt.str {
+			t.Errorf("parseTagFilter(%q).String() = %q, want %q", tt.input, f.String(), tt.str)
+		}
+		var got []string
+		for _, td := range todos {
+			if f.matches(td) {
+				got = append(got, td.Text)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("filter %q matches %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestTagSuggestions(t *testing.T) {
+	todos := []Todo{{Tags: []string{"home", "money"}}, {Tags: []string{"Money", "phone", ""}}}
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"", []string{"home", "money", "phone"}},
+		{"ho", []string{"home", "phone"}},
+		{"home #MON", []string{"money"}},
+		{"home,", []string{"home", "money", "phone"}},
+		{"xyz", nil},
+	}
+	for _, tt := range tests {
+		if got := tagSuggestions(todos, tt.input); !slices.Equal(got, tt.want) {
+			t.Errorf("tagSuggestions(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestModelTagFilter(t *testing.T) {
+	home := newTodo("Pay rent")
+	home.Tags = []string{"home"}
+	m := initialModel(newMemoryStore(home, newTodo("Write report")))
+
+	m = press(m, "t", "ho", "tab", "enter")
+	if len(m.rows) != 1 || m.todos[m.rows[0]].Text != "Pay rent" {
+		t.Errorf("filtered rows %v of %q", m.rows, todoTexts(m.todos))
+	}
+	if want := "Showing 1 todo(s) tagged #home. Press esc to clear."; m.status != want {
+		t.Errorf("status %q, want %q", m.status, want)
+	}
+
+	// Delete all only deletes what the filter shows.
+	m = press(m, "D", "y", "esc")
+	if got := todoTexts(m.todos); !slices.Equal(got, []string{"[ ] Write report"}) || len(m.rows) != 1 {
+		t.Errorf("after deleting the filtered todos, list is %q with %d rows", got, len(m.rows))
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// format reads and writes todos in another tool's file format, for the
+// import and export commands. read returns the todos it could read along
+// with any rows it had to reject.
+type format struct {
+	name  string
+	exts  []string // file extensions that select this format, e.g. ".txt"
+	read  func(r io.Reader, opts importOptions) ([]Todo, []badLine, error)
+	write func(w io.Writer, todos []Todo, opts exportOptions) error
+}
+
+// importOptions are the import command's settings that only some formats
+// use.
+type importOptions struct {
+	columns map[string]string // csv: lower-cased header to column name
+}
+
+// exportOptions are the export command's settings that only some formats
+// use.
+type exportOptions struct {
+	group string // markdown: heading to group todos under; see markdownGroups
+}
+
+var formats = []format{
+	{"todotxt", []string{".txt", ".todotxt"}, readTodoTxt, writeTodoTxt},
+	{"ical", []string{".ics", ".ical"}, readICS, writeICS},
+	{"markdown", []string{".md", ".markdown"}, readMarkdown, writeMarkdown},
+	{"csv", []string{".csv"}, readCSV, writeCSV},
+	{"taskwarrior", nil, readTaskwarrior, writeTaskwarrior},
+}
+
+// checkImportedIDs makes the IDs of imported todos safe to store alongside
+// existing ones. Only ULIDs, as go-do-it writes them, are kept: other
+// tools' IDs (todo.txt's "id:1", a calendar's UIDs) and repeats within the
+// file get a new ID, and parent links to them follow. Parents that are
+// neither in the file nor in existing are dropped, and so is any link that
+// would make a todo its own ancestor.
+func checkImportedIDs(imported, existing []Todo) {
+	renamed := make(map[string]string, len(imported))
+	taken := make(map[string]bool, len(imported))
+	for i := range imported {
+		t := &imported[i]
+		id := strings.ToUpper(t.ID)
+		if _, ok := decodeULID(id); !ok || taken[id] {
+			id = newID()
+		}
+		if _, ok := renamed[t.ID]; !ok && t.ID != "" {
+			renamed[t.ID] = id
+		}
+		taken[id] = true
+		t.ID = id
+	}
+	parents := make(map[string]string, len(existing)+len(imported))
+	for _, t := range existing {
+		parents[t.ID] = t.ParentID
+	}
+	for i := range imported {
+		t := &imported[i]
+		if t.ParentID == "" {
+			continue
+		}
+		if id, ok := renamed[t.ParentID]; ok {
+			t.ParentID = id
+		} else if _, ok := parents[strings.ToUpper(t.ParentID)]; ok {
+			t.ParentID = strings.ToUpper(t.ParentID)
+		} else {
+			t.ParentID = ""
+		}
+	}
+	for _, t := range imported {
+		parents[t.ID] = t.ParentID
+	}
+	for i := range imported {
+		t := &imported[i]
+		seen := map[string]bool{}
+		for p := parents[t.ID]; p != "" && !seen[p]; p = parents[p] {
+			if p == t.ID {
+				t.ParentID = ""
+				parents[t.ID] = ""
+				break
+			}
+			seen[p] = true
+		}
+	}
+}
+
+// formatNames lists the formats, for flag help.
+func formatNames() string {
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = f.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// findFormat returns the format with the given name or, if name is empty,
+// the one whose extension matches path.
+func findFormat(name, path string) (format, error) {
+	for _, f := range formats {
+		if name == f.name {
+			return f, nil
+		}
+		if name == "" {
+			for _, ext := range f.exts {
+				if strings.EqualFold(filepath.Ext(path), ext) {
+					return f, nil
+				}
+			}
+		}
+	}
+	if name == "" {
+		return format{}, fmt.Errorf("cannot tell the format of %q; use --format (%s)", path, formatNames())
+	}
+	return format{}, fmt.Errorf("unknown format %q (want %s)", name, formatNames())
+}
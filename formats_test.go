@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// roundTrip writes todos in the named format, reads them back and checks
+// that writing those gives the same output again.
+func roundTrip(t *testing.T, name string, todos []Todo, opts exportOptions) (string, []Todo) {
+	t.Helper()
+	f, err := findFormat(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := f.write(&out, todos, opts); err != nil {
+		t.Fatal(err)
+	}
+	got, rejected, err := f.read(strings.NewReader(out.String()), importOptions{})
+	if err != nil {
+		t.Fatalf("reading back:\n%s\n%v", out.String(), err)
+	}
+	for _, l := range rejected {
+		t.Errorf("line %d rejected: %v", l.line, l.err)
+	}
+	var again bytes.Buffer
+	if err := f.write(&again, got, opts); err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != out.String() {
+		t.Errorf("second export differs\nfirst:\n%s\nsecond:\n%s", out.String(), again.String())
+	}
+	return out.String(), got
+}
+
+// readString reads input in the named format.
+func readString(t *testing.T, name, input string) ([]Todo, []badLine) {
+	t.Helper()
+	f, err := findFormat(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	todos, rejected, err := f.read(strings.NewReader(input), importOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return todos, rejected
+}
+
+func TestFindFormat(t *testing.T) {
+	tests := []struct {
+		name, path, want string
+	}{
+		{"", "todo.txt", "todotxt"},
+		{"", "TODO.TXT", "todotxt"},
+		{"todotxt", "notes.md", "todotxt"},
+		{"", "nope.doc", ""},
+		{"nope", "todo.txt", ""},
+	}
+	for _, tt := range tests {
+		f, err := findFormat(tt.name, tt.path)
+		if got := f.name; got != tt.want || (err != nil) != (tt.want == "") {
+			t.Errorf("findFormat(%q, %q) = %q, %v; want %q", tt.name, tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestCheckImportedIDs(t *testing.T) {
+	const (
+		ulid1 = "01JA00000000000000000000B1"
+		ulid2 = "01JA00000000000000000000B2"
+	)
+	existing := []Todo{{ID: ulid1}, {ID: ulid2, ParentID: ulid1}}
+	tests := []struct {
+		name     string
+		imported []Todo
+		// want uses "new" for an ID minted on import, "new1", "new2"...
+		// when several are, and the same name for the same ID.
+		want []string // "ID<parent" per todo
+	}{
+		{
+			name:     "ULIDs are kept and upper-cased",
+			imported: []Todo{{ID: ulid1}, {ID: strings.ToLower(ulid2)}},
+			want:     []string{ulid1 + "<", ulid2 + "<"},
+		},
+		{
+			name:     "foreign IDs are replaced and parents follow",
+			imported: []Todo{{ID: "1"}, {ID: "2", ParentID: "1"}},
+			want:     []string{"new1<", "new2<new1"},
+		},
+		{
+			name:     "empty IDs get one without adopting children",
+			imported: []Todo{{}, {ID: "2"}},
+			want:     []string{"new1<", "new2<"},
+		},
+		{
+			name:     "repeated IDs get a new one",
+			imported: []Todo{{ID: "1"}, {ID: "1"}, {ID: ulid1}, {ID: ulid1}, {ID: "3", ParentID: "1"}},
+			want:     []string{"new1<", "new2<", ulid1 + "<", "new3<", "new4<new1"},
+		},
+		{
+			name:     "parents may be existing todos",
+			imported: []Todo{{ID: "1", ParentID: strings.ToLower(ulid2)}},
+			want:     []string{"new1<" + ulid2},
+		},
+		{
+			name:     "unknown parents are dropped",
+			imported: []Todo{{ID: "1", ParentID: "nowhere"}},
+			want:     []string{"new1<"},
+		},
+		{
+			name:     "own parent",
+			imported: []Todo{{ID: "1", ParentID: "1"}},
+			want:     []string{"new1<"},
+		},
+		{
+			name:     "cycle in the file",
+			imported: []Todo{{ID: "1", ParentID: "3"}, {ID: "2", ParentID: "1"}, {ID: "3", ParentID: "2"}},
+			want:     []string{"new1<", "new2<new1", "new3<new2"},
+		},
+		{
+			name:     "cycle through an existing todo",
+			imported: []Todo{{ID: ulid1, ParentID: ulid2}},
+			want:     []string{ulid1 + "<"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imported := slices.Clone(tt.imported)
+			checkImportedIDs(imported, existing)
+			names := map[string]string{ulid1: ulid1, ulid2: ulid2, "": ""}
+			name := func(id string) string {
+				if n, ok := names[id]; ok {
+					return n
+				}
+				if _, ok := decodeULID(id); !ok || id != strings.ToUpper(id) {
+					t.Errorf("minted ID %q is not a ULID", id)
+				}
+				names[id] = fmt.Sprintf("new%d", len(names)-2)
+				return names[id]
+			}
+			var got []string
+			for _, td := range imported {
+				got = append(got, name(td.ID)+"<"+name(td.ParentID))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,16 +1,82 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
-	p := tea.NewProgram(initialModel())
-	if err := p.Start(); err != nil {
+	file := flag.String("file", "", "todo file (default $GODOIT_FILE or $XDG_DATA_HOME/go-do-it/todolist.txt)")
+	backend := flag.String("backend", "file", "storage backend: file or sqlite")
+	lock := flag.String("lock", "", "if another instance has the todo file open: fail, wait or readonly (default fail, or wait for commands unless the TUI has it)")
+	lockTimeout := flag.Duration("lock-timeout", 10*time.Second, "how long --lock wait waits for another command before giving up; 0 waits for ever")
+	list := flag.String("list", defaultList, "named list to open")
+	flag.Usage = func() {
+		printCommandUsage(flag.CommandLine.Output())
+		fmt.Fprintln(flag.CommandLine.Output(), "\nGlobal flags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Commands hold the list only for a moment, so by default one waits
+	// for another to finish. The TUI holds it until it quits; a command
+	// that finds it open fails straight away and says so.
+	locking := lockOptions{onLocked: lockFail, timeout: *lockTimeout, tui: flag.NArg() == 0}
+	if !locking.tui {
+		locking.onLocked = lockWait
+	}
+	if *lock != "" {
+		var err error
+		if locking.onLocked, err = parseLockMode(*lock); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(2)
+		}
+	}
+	path, err := dataFile(*file)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	lists := newListSet(*backend, path, locking)
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(lists, *list, flag.Args()))
+	}
+	store, err := lists.open(*list)
+	if err != nil {
+		fmt.Println("Error opening todos:", err)
+		os.Exit(1)
+	}
+
+	m := initialModel(store)
+	m.lists = lists
+	m.listName = *list
+	m.loadPrefs()
+	p := tea.NewProgram(m)
+	final, err := p.Run()
+	if fm, ok := final.(model); ok {
+		fm.store.Close()
+	} else {
+		store.Close()
+	}
+	if err != nil {
 		fmt.Println("Error running program:", err)
 		os.Exit(1)
 	}
 }
+
+// openStore returns the Store for the named backend. The SQLite database
+// lives next to the todo file and is seeded from it on first use.
+func openStore(backend, path string, lock lockOptions) (Store, error) {
+	switch backend {
+	case "file":
+		return openFileStore(path, lock)
+	case "sqlite":
+		return openSQLiteStore(sqlitePath(path), path)
+	default:
+		return nil, fmt.Errorf("unknown backend %q", backend)
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// historyLimit caps how many steps can be undone.
+const historyLimit = 100
+
+// errUnsaved stops a command that touches another list while this one has
+// changes that failed to save.
+var errUnsaved = errors.New("this list has unsaved changes; press R to retry saving first")
+
+// command is a reversible change to the todo list. apply and revert update
+// m.todos and persist the change; storage failures are reported through
+// m.persist, so an error return means the command did not take effect.
+type command interface {
+	apply(m *model) error
+	revert(m *model) error
+	String() string
+}
+
+// history holds applied commands for undo and undone ones for redo.
+type history struct {
+	done   []command
+	undone []command
+}
+
+// exec applies c and records it for undo, dropping anything redoable.
+func (m *model) exec(c command) {
+	if err := c.apply(m); err != nil {
+		m.status = fmt.Sprintf("Could not %s: %v", c, err)
+		return
+	}
+	m.history.done = append(m.history.done, c)
+	if len(m.history.done) > historyLimit {
+		m.history.done = m.history.done[1:]
+	}
+	m.history.undone = nil
+}
+
+func (m *model) undo() {
+	h := &m.history
+	if len(h.done) == 0 {
+		m.status = "Nothing to undo."
+		return
+	}
+	c := h.done[len(h.done)-1]
+	m.status = fmt.Sprintf("Undid %s (%d more to undo).", c, len(h.done)-1)
+	if err := c.revert(m); err != nil {
+		m.status = fmt.Sprintf("Could not undo %s: %v", c, err)
+		return
+	}
+	h.done = h.done[:len(h.done)-1]
+	h.undone = append(h.undone, c)
+}
+
+func (m *model) redo() {
+	h := &m.history
+	if len(h.undone) == 0 {
+		m.status = "Nothing to redo."
+		return
+	}
+	c := h.undone[len(h.undone)-1]
+	m.status = fmt.Sprintf("Redid %s (%d more to redo).", c, len(h.undone)-1)
+	if err := c.apply(m); err != nil {
+		m.status = fmt.Sprintf("Could not redo %s: %v", c, err)
+		return
+	}
+	h.undone = h.undone[:len(h.undone)-1]
+	h.done = append(h.done, c)
+}
+
+// change is one step in the undo history, as the detail pane lists them.
+type change struct {
+	at   time.Time
+	what string
+}
+
+// changesTo returns the steps that can be undone which changed the todo
+// with id, most recent first.
+func (h history) changesTo(id string) []change {
+	var changes []change
+	for i := len(h.done) - 1; i >= 0; i-- {
+		if c, ok := changeTo(h.done[i], id); ok {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// changeTo reports whether c added or updated the todo with id, and how.
+// Deleted and moved todos are no longer in the list to ask about.
+func changeTo(c command, id string) (change, bool) {
+	switch c := c.(type) {
+	case *putCmd:
+		return change{c.after.UpdatedAt, c.verb}, c.after.ID == id
+	case *batchCmd:
+		for _, sub := range c.cmds {
+			if ch, ok := changeTo(sub, id); ok {
+				return ch, true
+			}
+		}
+	}
+	return change{}, false
+}
+
+// putCmd adds a todo (before is nil) or replaces one with a new version.
+type putCmd struct {
+	verb   string
+	before *Todo
+	after  Todo
+}
+
+func addCmd(t Todo) *putCmd {
+	return &putCmd{verb: "add", after: t}
+}
+
+func updateCmd(verb string, before, after Todo) *putCmd {
+	return &putCmd{verb: verb, before: &before, after: after}
+}
+
+func (c *putCmd) apply(m *model) error {
+	m.todos = putTodo(m.todos, c.after)
+	m.reveal(c.after.ID)
+	m.persist(func() error { return m.store.Put(c.after) })
+	return nil
+}
+
+func (c *putCmd) revert(m *model) error {
+	if c.before == nil {
+		m.todos, _ = deleteTodo(m.todos, c.after.ID)
+		m.refresh()
+		m.persist(func() error { return m.store.Delete(c.after.ID) })
+		return nil
+	}
+	m.todos = putTodo(m.todos, *c.before)
+	m.reveal(c.before.ID)
+	m.persist(func() error { return m.store.Put(*c.before) })
+	return nil
+}
+
+func (c *putCmd) String() string {
+	return fmt.Sprintf("%s %s", c.verb, quoteText(c.after.Text))
+}
+
+// batchCmd applies several commands as one undoable step.
+type batchCmd struct {
+	verb string
+	cmds []command
+}
+
+func (c *batchCmd) apply(m *model) error {
+	for i, sub := range c.cmds {
+		if err := sub.apply(m); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				c.cmds[j].revert(m)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *batchCmd) revert(m *model) error {
+	for i := len(c.cmds) - 1; i >= 0; i-- {
+		if err := c.cmds[i].revert(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *batchCmd) String() string {
+	return c.verb
+}
+
+// deleteCmd removes one or more todos, remembering where they were.
+// Deleting a todo deletes its subtasks with it.
+type deleteCmd struct {
+	todos   []Todo
+	indexes []int // ascending positions in m.todos before deletion
+}
+
+// newDeleteCmd deletes the todos at the given ascending indexes.
+func newDeleteCmd(todos []Todo, indexes []int) *deleteCmd {
+	c := &deleteCmd{indexes: indexes}
+	for _, i := range indexes {
+		c.todos = append(c.todos, todos[i])
+	}
+	return c
+}
+
+func (c *deleteCmd) apply(m *model) error {
+	for _, t := range c.todos {
+		m.todos, _ = deleteTodo(m.todos, t.ID)
+	}
+	m.refresh()
+	if len(c.todos) == 1 {
+		m.persist(func() error { return m.store.Delete(c.todos[0].ID) })
+	} else {
+		m.persist(func() error { return m.store.Save(m.todos) })
+	}
+	return nil
+}
+
+func (c *deleteCmd) revert(m *model) error {
+	for k, i := range c.indexes {
+		m.todos = insertAt(m.todos, i, c.todos[k])
+	}
+	m.reveal(c.todos[0].ID)
+	m.persist(func() error { return m.store.Save(m.todos) })
+	return nil
+}
+
+func (c *deleteCmd) String() string {
+	if len(c.todos) == 1 {
+		return "delete " + quoteText(c.todos[0].Text)
+	}
+	return fmt.Sprintf("delete of %d todos", len(c.todos))
+}
+
+// moveCmd moves a todo and its subtasks from the current list to another.
+type moveCmd struct {
+	root    string // ID of the todo picked, which becomes top-level there
+	todos   []Todo
+	indexes []int // ascending positions in m.todos before the move
+	list    string
+}
+
+// newMoveCmd moves the todo with root and its subtasks to list.
+func newMoveCmd(todos []Todo, root, list string) *moveCmd {
+	c := &moveCmd{root: root, indexes: subtree(todos, root), list: list}
+	for _, i := range c.indexes {
+		c.todos = append(c.todos, todos[i])
+	}
+	return c
+}
+
+func (c *moveCmd) apply(m *model) error {
+	if m.pending != retryNone {
+		return errUnsaved
+	}
+	target, err := m.lists.open(c.list)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+	// Take the todos out of this list first: if that fails they stay here,
+	// and if adding them to the other list fails they are put back, so they
+	// never end up in both.
+	before := m.todos
+	m.todos = slices.Clone(m.todos)
+	for _, t := range c.todos {
+		m.todos, _ = deleteTodo(m.todos, t.ID)
+	}
+	if len(c.todos) == 1 {
+		err = m.store.Delete(c.todos[0].ID)
+	} else {
+		err = m.store.Save(m.todos)
+	}
+	if err != nil {
+		m.todos = before
+		return err
+	}
+	for _, t := range c.todos {
+		if t.ID == c.root {
+			t = reparent(t, "")
+		}
+		if err := target.Put(t); err != nil {
+			m.todos = before
+			m.persist(func() error { return m.store.Save(m.todos) })
+			return err
+		}
+	}
+	m.refresh()
+	return nil
+}
+
+func (c *moveCmd) revert(m *model) error {
+	if m.pending != retryNone {
+		return errUnsaved
+	}
+	target, err := m.lists.open(c.list)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+	before := m.todos
+	m.todos = slices.Clone(m.todos)
+	for k, i := range c.indexes {
+		m.todos = insertAt(m.todos, i, c.todos[k])
+	}
+	if err := m.store.Save(m.todos); err != nil {
+		m.todos = before
+		return err
+	}
+	for _, t := range c.todos {
+		if err := target.Delete(t.ID); err != nil {
+			m.todos = before
+			m.persist(func() error { return m.store.Save(m.todos) })
+			return err
+		}
+	}
+	m.reveal(c.root)
+	return nil
+}
+
+func (c *moveCmd) String() string {
+	text := ""
+	for _, t := range c.todos {
+		if t.ID == c.root {
+			text = t.Text
+		}
+	}
+	return fmt.Sprintf("move %s to %q", quoteText(text), c.list)
+}
+
+// insertAt inserts t at index i, or appends it if i is past the end.
+func insertAt(todos []Todo, i int, t Todo) []Todo {
+	if i < 0 || i > len(todos) {
+		i = len(todos)
+	}
+	return append(todos[:i], append([]Todo{t}, todos[i:]...)...)
+}
+
+// quoteText quotes a todo's text for status messages, shortening long text.
+func quoteText(s string) string {
+	if r := []rune(s); len(r) > 30 {
+		s = strings.TrimSpace(string(r[:27])) + "..."
+	}
+	return fmt.Sprintf("%q", s)
+}
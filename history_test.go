@@ -0,0 +1,98 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestUndoRedo(t *testing.T) {
+	store := newMemoryStore(newTodo("Pay rent"), newTodo("Call the bank"))
+	m := initialModel(store)
+	states := [][]string{todoTexts(store.todos)}
+	for _, keys := range [][]string{
+		{"a", "Buy stamps", "enter"},
+		{"k", "k", " "},
+		{"j", "d", "y"},
+		{"e", "ctrl+u", "Buy more stamps", "enter", "enter", "enter", "enter", "enter"},
+		{"D", "y"},
+	} {
+		m = press(m, keys...)
+		states = append(states, todoTexts(store.todos))
+	}
+	check := func(step string, want []string) {
+		t.Helper()
+		if got := todoTexts(store.todos); !slices.Equal(got, want) {
+			t.Errorf("after %s, stored %q, want %q", step, got, want)
+		}
+		if got := todoTexts(m.todos); !slices.Equal(got, want) {
+			t.Errorf("after %s, shown %q, want %q", step, got, want)
+		}
+	}
+	check("the changes", nil)
+
+	for i := len(states) - 2; i >= 0; i-- {
+		m = press(m, "u")
+		check("undo "+m.status, states[i])
+	}
+	if m = press(m, "u"); m.status != "Nothing to undo." {
+		t.Errorf("undo past the start: status %q", m.status)
+	}
+	for i := 1; i < len(states); i++ {
+		m = press(m, "ctrl+r")
+		check("redo "+m.status, states[i])
+	}
+	if m = press(m, "ctrl+r"); m.status != "Nothing to redo." {
+		t.Errorf("redo past the end: status %q", m.status)
+	}
+
+	// A new change drops whatever could have been redone.
+	m = press(m, "u", "a", "Water plants", "enter", "ctrl+r")
+	if m.status != "Nothing to redo." {
+		t.Errorf("redo after a new change: status %q", m.status)
+	}
+	check("a new change", []string{"[x] Pay rent", "[ ] Buy more stamps", "[ ] Water plants"})
+}
+
+func TestUndoStatus(t *testing.T) {
+	m := initialModel(newMemoryStore(newTodo("Pay rent")))
+	m = press(m, " ", " ", "u")
+	if want := `Undid toggle "Pay rent" (1 more to undo).`; m.status != want {
+		t.Errorf("status %q, want %q", m.status, want)
+	}
+	m = press(m, "ctrl+r")
+	if want := `Redid toggle "Pay rent" (0 more to redo).`; m.status != want {
+		t.Errorf("status %q, want %q", m.status, want)
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	m := initialModel(newMemoryStore(newTodo("Pay rent")))
+	for range historyLimit + 5 {
+		m = press(m, " ")
+	}
+	undone := 0
+	for m = press(m, "u"); !strings.HasPrefix(m.status, "Nothing"); m = press(m, "u") {
+		undone++
+	}
+	if undone != historyLimit {
+		t.Errorf("undid %d steps, want %d", undone, historyLimit)
+	}
+}
+
+func TestReloadClearsHistory(t *testing.T) {
+	store := newMemoryStore(newTodo("Pay rent"))
+	m := initialModel(store)
+	m = press(m, "a", "Buy stamps", "enter")
+
+	// Another process rewrites the list; undoing the add after a reload
+	// must not touch the list that was loaded.
+	store.todos = []Todo{newTodo("Water plants")}
+	m = press(m, "r", "u")
+	if m.status != "Nothing to undo." {
+		t.Errorf("undo after reload: status %q", m.status)
+	}
+	if got, want := todoTexts(store.todos), []string{"[ ] Water plants"}; !slices.Equal(got, want) {
+		t.Errorf("stored %q, want %q", got, want)
+	}
+}
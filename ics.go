@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// iCalendar (RFC 5545) files hold todos as VTODO components. Each todo's ID
+// is its UID, so exporting, editing in a calendar app and importing again
+// updates the same todos. Priorities map onto iCalendar's 1-9 scale as
+// urgent 1, medium 5 and low 9; on import 1-4 is urgent, 5 (or none) is
+// medium and 6-9 is low. Notes go in DESCRIPTION, and a subtask points at
+// its parent with RELATED-TO.
+
+const icsTimeLayout = "20060102T150405Z"
+
+func icsPriority(priority string) int {
+	switch priority {
+	case "urgent":
+		return 1
+	case "low":
+		return 9
+	}
+	return 5
+}
+
+func priorityFromICS(n int) string {
+	switch {
+	case n >= 1 && n <= 4:
+		return "urgent"
+	case n >= 6 && n <= 9:
+		return "low"
+	}
+	return "medium"
+}
+
+func writeICS(w io.Writer, todos []Todo, _ exportOptions) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeICSLine(bw, name+":"+value)
+	}
+	stamp := time.Now().UTC().Format(icsTimeLayout)
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//go-do-it//go-do-it//EN")
+	for _, t := range todos {
+		line("BEGIN", "VTODO")
+		line("UID", icsEscape(t.ID))
+		line("DTSTAMP", stamp)
+		if !t.CreatedAt.IsZero() {
+			line("CREATED", t.CreatedAt.UTC().Format(icsTimeLayout))
+		}
+		if !t.UpdatedAt.IsZero() {
+			line("LAST-MODIFIED", t.UpdatedAt.UTC().Format(icsTimeLayout))
+		}
+		line("SUMMARY", icsEscape(t.Text))
+		if t.Notes != "" {
+			line("DESCRIPTION", icsEscape(t.Notes))
+		}
+		if due, err := time.Parse(dateLayout, t.DueDate); err == nil {
+			line("DUE;VALUE=DATE", due.Format("20060102"))
+		}
+		line("PRIORITY", strconv.Itoa(icsPriority(t.Priority)))
+		if len(t.Tags) > 0 {
+			tags := make([]string, len(t.Tags))
+			for i, tag := range t.Tags {
+				tags[i] = icsEscape(tag)
+			}
+			line("CATEGORIES", strings.Join(tags, ","))
+		}
+		if t.Done {
+			line("STATUS", "COMPLETED")
+			if !t.CompletedAt.IsZero() {
+				line("COMPLETED", t.CompletedAt.UTC().Format(icsTimeLayout))
+			}
+		} else {
+			line("STATUS", "NEEDS-ACTION")
+		}
+		if t.Recurrence != "" {
+			line("RRULE", t.Recurrence)
+		}
+		if t.ParentID != "" {
+			line("RELATED-TO;RELTYPE=PARENT", icsEscape(t.ParentID))
+		}
+		line("END", "VTODO")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// writeICSLine writes a content line ending in CRLF, folded so no line is
+// longer than 75 bytes, without splitting a UTF-8 character.
+func writeICSLine(w *bufio.Writer, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		limit = 74 // the leading space counts
+	}
+	w.WriteString(s + "\r\n")
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+func icsEscape(s string) string {
+	return icsEscaper.Replace(s)
+}
+
+// icsUnescape undoes icsEscape.
+func icsUnescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// icsSplit splits a list value on commas that are not escaped.
+func icsSplit(s string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// icsProperty is one content line: NAME;PARAM=VALUE:value.
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseICSLine splits an unfolded content line. Parameter values may be
+// quoted and contain colons.
+func parseICSLine(s string) (icsProperty, bool) {
+	p := icsProperty{params: map[string]string{}}
+	quoted := false
+	colon := -1
+	for i := 0; i < len(s) && colon < 0; i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				colon = i
+			}
+		}
+	}
+	if colon < 0 {
+		return p, false
+	}
+	head := strings.Split(s[:colon], ";")
+	p.name = strings.ToUpper(head[0])
+	for _, param := range head[1:] {
+		if k, v, ok := strings.Cut(param, "="); ok {
+			p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	p.value = s[colon+1:]
+	return p, true
+}
+
+// parseICSTime reads a DATE or DATE-TIME value. UTC times are converted to
+// local time; floating and TZID times are taken as they are.
+func parseICSTime(value string) (time.Time, error) {
+	if t, err := time.Parse(icsTimeLayout, value); err == nil {
+		return t.Local(), nil
+	}
+	for _, layout := range []string{"20060102T150405", "20060102"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("bad date %q", value)
+}
+
+// readICS reads the VTODOs of a calendar. A VTODO with a value that cannot
+// be read, or without a summary, is rejected as a whole, reported at its
+// BEGIN line.
+func readICS(r io.Reader, _ importOptions) ([]Todo, []badLine, error) {
+	var lines []string
+	var starts []int // line number of each unfolded line
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		s := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(s, " ") || strings.HasPrefix(s, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += s[1:]
+			continue
+		}
+		lines = append(lines, s)
+		starts = append(starts, n)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var todos []Todo
+	var bad []badLine
+	var cur *Todo
+	var begin int    // line of the current VTODO's BEGIN
+	var curErr error // first problem with the current VTODO
+	for i, s := range lines {
+		if s == "" {
+			continue
+		}
+		n := starts[i]
+		p, ok := parseICSLine(s)
+		if !ok {
+			if cur == nil {
+				return nil, nil, fmt.Errorf("line %d: not an iCalendar content line: %q", n, s)
+			}
+			if curErr == nil {
+				curErr = fmt.Errorf("line %d: not an iCalendar content line: %q", n, s)
+			}
+			continue
+		}
+		if p.name == "BEGIN" && strings.EqualFold(p.value, "VTODO") {
+			if cur != nil {
+				bad = append(bad, badLine{line: begin, text: cur.Text, err: errors.New("no END:VTODO")})
+			}
+			cur, begin, curErr = &Todo{Priority: "medium"}, n, nil
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		// Alarms and other components nested in a VTODO are skipped.
+		if p.name == "BEGIN" || p.name == "END" {
+			if p.name == "END" && strings.EqualFold(p.value, "VTODO") {
+				if curErr == nil && cur.Text == "" {
+					curErr = errors.New("no SUMMARY")
+				}
+				if curErr != nil {
+					bad = append(bad, badLine{line: begin, text: cur.Text, err: curErr})
+				} else {
+					todos = append(todos, *cur)
+				}
+				cur = nil
+			}
+			continue
+		}
+		if err := setICSProperty(cur, p); err != nil && curErr == nil {
+			curErr = fmt.Errorf("line %d: %v", n, err)
+		}
+	}
+	if cur != nil {
+		bad = append(bad, badLine{line: begin, text: cur.Text, err: errors.New("no END:VTODO")})
+	}
+	return todos, bad, nil
+}
+
+func setICSProperty(t *Todo, p icsProperty) error {
+	var err error
+	switch p.name {
+	case "UID":
+		t.ID = icsUnescape(p.value)
+	case "SUMMARY":
+		t.Text = strings.Join(strings.Fields(icsUnescape(p.value)), " ")
+	case "DESCRIPTION":
+		t.Notes = strings.TrimSpace(icsUnescape(p.value))
+	case "DUE":
+		var due time.Time
+		if due, err = parseICSTime(p.value); err == nil {
+			t.DueDate = due.Format(dateLayout)
+		}
+	case "PRIORITY":
+		var n int
+		if n, err = strconv.Atoi(strings.TrimSpace(p.value)); err == nil {
+			t.Priority = priorityFromICS(n)
+		}
+	case "CATEGORIES":
+		for _, tag := range icsSplit(p.value) {
+			if tag = strings.TrimSpace(icsUnescape(tag)); tag != "" && !hasTag(*t, tag) {
+				t.Tags = append(t.Tags, tag)
+			}
+		}
+	case "STATUS":
+		t.Done = strings.EqualFold(p.value, "COMPLETED")
+	case "COMPLETED":
+		if t.CompletedAt, err = parseICSTime(p.value); err == nil {
+			t.Done = true
+		}
+	case "CREATED":
+		t.CreatedAt, err = parseICSTime(p.value)
+	case "LAST-MODIFIED":
+		t.UpdatedAt, err = parseICSTime(p.value)
+	case "RRULE":
+		// Rules using parts go-do-it cannot follow are dropped rather
+		// than failing the whole import.
+		if rule, err := recurrenceInput(p.value); err == nil {
+			t.Recurrence = rule
+		}
+	case "RELATED-TO":
+		if rel := strings.ToUpper(p.params["RELTYPE"]); rel == "" || rel == "PARENT" {
+			t.ParentID = icsUnescape(p.value)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", p.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// icsStamp matches DTSTAMP, the one line that changes between exports.
+var icsStamp = regexp.MustCompile(`(?m)^DTSTAMP:.*$`)
+
+func TestICSRoundTrip(t *testing.T) {
+	todos := sampleTodos()
+	todos[0].Text = "Pay rent; then, tell the landlord \\ agent — " + strings.Repeat("ünïcödé ", 12)
+	todos[0].Text = strings.TrimSpace(todos[0].Text)
+	todos[1].Tags = []string{"home", "a,b"}
+	var out bytes.Buffer
+	if err := writeICS(&out, todos, exportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(out.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 bytes: %q", line)
+		}
+	}
+	got, _ := readString(t, "ical", out.String())
+	if !slices.Equal(summaries(got), summaries(todos)) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(summaries(got), "\n"), strings.Join(summaries(todos), "\n"))
+	}
+	for i, td := range got {
+		if !td.CreatedAt.Equal(todos[i].CreatedAt) || !td.UpdatedAt.Equal(todos[i].UpdatedAt) || !td.CompletedAt.Equal(todos[i].CompletedAt) {
+			t.Errorf("todo %d: times %v %v %v, want %v %v %v", i, td.CreatedAt, td.UpdatedAt, td.CompletedAt,
+				todos[i].CreatedAt, todos[i].UpdatedAt, todos[i].CompletedAt)
+		}
+	}
+	var again bytes.Buffer
+	if err := writeICS(&again, got, exportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := icsStamp.ReplaceAllString(out.String(), ""), icsStamp.ReplaceAllString(again.String(), ""); a != b {
+		t.Errorf("second export differs\nfirst:\n%s\nsecond:\n%s", a, b)
+	}
+}
+
+func TestReadICS(t *testing.T) {
+	input := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:event-1",
+		"SUMMARY:Not a todo",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:abc@example.com",
+		"SUMMARY:Write the ",
+		" report",
+		"DUE;TZID=Europe/Berlin:20261101T090000",
+		"PRIORITY:3",
+		"CATEGORIES:work,Q4",
+		"CATEGORIES:work",
+		"RRULE:FREQ=MONTHLY;BYDAY=1MO",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"END:VALARM",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"UID:def@example.com",
+		"SUMMARY:Proofread",
+		"PRIORITY:7",
+		"STATUS:COMPLETED",
+		"RELATED-TO:abc@example.com",
+		"RRULE:FREQ=WEEKLY;INTERVAL=2",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"SUMMARY:No priority",
+		"RELATED-TO;RELTYPE=CHILD:xyz",
+		"END:VTODO",
+		"BEGIN:VTODO", // line 32
+		"SUMMARY:Bad due",
+		"DUE:tomorrow",
+		"END:VTODO",
+		"BEGIN:VTODO", // line 36
+		"SUMMARY:Bad priority",
+		"PRIORITY:high",
+		"END:VTODO",
+		"BEGIN:VTODO", // line 40
+		"no colon here",
+		"SUMMARY:Bad line",
+		"END:VTODO",
+		"BEGIN:VTODO", // line 44
+		"SUMMARY:  ",
+		"END:VTODO",
+		"BEGIN:VTODO", // line 47
+		"UID:no-summary",
+		"END:VTODO",
+		"BEGIN:VTODO", // line 50
+		"SUMMARY:Created ",
+		" later",
+		"CREATED:sometime",
+		"END:VTODO",
+		"END:VCALENDAR",
+		"BEGIN:VTODO", // line 56
+		"SUMMARY:Unfinished",
+	}, "\r\n")
+	got, bad := readString(t, "ical", input)
+	want := []string{
+		`abc@example.com [ ] "Write the report" urgent due=2026-11-01 tags=[work Q4] parent= rec=`,
+		`def@example.com [x] "Proofread" low due= tags=[] parent=abc@example.com rec=FREQ=WEEKLY;INTERVAL=2`,
+		` [ ] "No priority" medium due= tags=[] parent= rec=`,
+	}
+	if !slices.Equal(summaries(got), want) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(summaries(got), "\n"), strings.Join(want, "\n"))
+	}
+	var lines []int
+	for _, b := range bad {
+		lines = append(lines, b.line)
+	}
+	if want := []int{32, 36, 40, 44, 47, 50, 56}; !slices.Equal(lines, want) {
+		t.Errorf("rejected lines %v, want %v", lines, want)
+	}
+	if len(bad) > 0 && !strings.Contains(bad[0].err.Error(), "line 34") {
+		t.Errorf("rejection %q does not name the bad line", bad[0].err)
+	}
+}
+
+func TestReadICSErrors(t *testing.T) {
+	for _, input := range []string{
+		"just some text\r\n",
+		"BEGIN:VCALENDAR\r\nno colon here\r\nEND:VCALENDAR\r\n",
+	} {
+		if todos, _, err := readICS(strings.NewReader(input), importOptions{}); err == nil {
+			t.Errorf("readICS(%q) = %v, want an error", input, todos)
+		}
+	}
+}
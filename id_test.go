@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewID(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 100 {
+		id := newID()
+		if len(id) != 26 || strings.Trim(id, crockford) != "" {
+			t.Fatalf("newID() = %q, not a ULID", id)
+		}
+		if seen[id] {
+			t.Fatalf("newID() repeated %q", id)
+		}
+		seen[id] = true
+	}
+
+	before := newID()
+	time.Sleep(2 * time.Millisecond)
+	if after := newID(); after <= before {
+		t.Errorf("ID %q made later sorts before %q", after, before)
+	}
+}
+
+func TestEncodeULID(t *testing.T) {
+	var ones [16]byte
+	for i := range ones {
+		ones[i] = 0xff
+	}
+	tests := []struct {
+		b    [16]byte
+		want string
+	}{
+		{[16]byte{}, "00000000000000000000000000"},
+		{[16]byte{15: 1}, "00000000000000000000000001"},
+		{ones, "7ZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+		{[16]byte{5: 1}, "00000000010000000000000000"}, // the timestamp is the first 10 characters
+	}
+	for _, tt := range tests {
+		if got := encodeULID(tt.b); got != tt.want {
+			t.Errorf("encodeULID(%x) = %q, want %q", tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	defaultWidth  = 80 // until the terminal reports its size
+	taskMinWidth  = 16
+	tagsMinWidth  = 8
+	tagsMaxWidth  = 30
+	dueWidth      = 10 // YYYY-MM-DD
+	priorityWidth = 8  // [urgent]
+	cursorWidth   = 2  // "> " in front of each row
+)
+
+// tableLayout is how wide each column of the todo table is. Columns with
+// width 0 are hidden.
+type tableLayout struct {
+	num, task, due, priority, tags int
+}
+
+// newTableLayout fits the table into width cells. taskWidth and tagsWidth
+// are the widths of the widest todo and tags cells, so that no column is
+// wider than it needs to be; the tags column is left out when no todo has
+// tags. The todo text gets what the other columns leave over, and when
+// that is too little the tags column goes first, then the row numbers, the
+// priority and the due date.
+func newTableLayout(width, rows, taskWidth, tagsWidth int) tableLayout {
+	l := tableLayout{num: len(strconv.Itoa(rows)), due: dueWidth, priority: priorityWidth}
+	rest := func() int {
+		used := cursorWidth
+		for _, w := range []int{l.num, l.due, l.priority, l.tags} {
+			if w > 0 {
+				used += w + 1
+			}
+		}
+		return width - used
+	}
+	if tagsWidth > 0 {
+		tagsWidth = min(max(tagsWidth, tagsMinWidth), tagsMaxWidth)
+		// Tags get up to a third of the room, or what the text leaves.
+		l.tags = max(min(tagsWidth, (rest()-1)/3), min(tagsWidth, rest()-1-max(taskWidth, taskMinWidth)))
+		if l.tags < tagsMinWidth {
+			l.tags = 0
+		}
+	}
+	for _, w := range []*int{&l.num, &l.priority, &l.due} {
+		if rest() >= taskMinWidth {
+			break
+		}
+		*w = 0
+	}
+	l.task = max(min(rest(), max(taskWidth, taskMinWidth)), 4)
+	return l
+}
+
+// width is how many cells a row of the table takes.
+func (l tableLayout) width() int {
+	w := cursorWidth + l.task
+	for _, c := range []int{l.num, l.due, l.priority, l.tags} {
+		if c > 0 {
+			w += c + 1
+		}
+	}
+	return w
+}
+
+// row lays out the cells of one row after the cursor column. Cells may be
+// styled; they are cut to their column with an ellipsis and padded.
+func (l tableLayout) row(cursor, num, task, due, priority, tags string) string {
+	cells := []string{cursor}
+	for _, c := range []struct {
+		width int
+		text  string
+	}{{l.num, num}, {l.task, task}, {l.due, due}, {l.priority, priority}, {l.tags, tags}} {
+		if c.width > 0 {
+			cells = append(cells, padRight(ansi.Truncate(c.text, c.width, "…"), c.width))
+		}
+	}
+	return cells[0] + strings.Join(cells[1:], " ")
+}
+
+// termWidth is the terminal's width, or a guess until it is known.
+func (m *model) termWidth() int {
+	if m.width == 0 {
+		return defaultWidth
+	}
+	return m.width
+}
+
+// tableWidth is how wide the todo table may be: the whole terminal, less
+// the detail pane when there is room for it alongside.
+func (m *model) tableWidth() int {
+	width := m.termWidth()
+	if pane := max(detailPaneWidth, width*2/5); m.showDetail && width-pane-1 >= defaultWidth*3/4 {
+		return width - pane - 1
+	}
+	return width
+}
+
+// wrapWidth wraps s to the terminal's width at word boundaries.
+func (m *model) wrapWidth(s string) string {
+	if m.width == 0 {
+		return s
+	}
+	return lipgloss.NewStyle().Width(m.width).Render(s)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNewTableLayout(t *testing.T) {
+	tests := []struct {
+		name                    string
+		width, rows, task, tags int
+		want                    tableLayout
+	}{
+		{"everything fits", 80, 9, 20, 10, tableLayout{num: 1, task: 20, due: 10, priority: 8, tags: 10}},
+		{"no tags", 40, 9, 50, 0, tableLayout{num: 1, task: 16, due: 10, priority: 8}},
+		{"narrow tags widen to the minimum", 80, 9, 20, 3, tableLayout{num: 1, task: 20, due: 10, priority: 8, tags: 8}},
+		{"wide tags are capped", 120, 9, 20, 60, tableLayout{num: 1, task: 20, due: 10, priority: 8, tags: 30}},
+		{"tags, numbers and priority go first", 30, 100, 50, 20, tableLayout{task: 17, due: 10}},
+		{"only the text is left", 10, 9, 50, 0, tableLayout{task: 8}},
+		{"text never goes below four cells", 3, 9, 50, 0, tableLayout{task: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newTableLayout(tt.width, tt.rows, tt.task, tt.tags)
+			if got != tt.want {
+				t.Errorf("newTableLayout(%d, %d, %d, %d) = %+v, want %+v", tt.width, tt.rows, tt.task, tt.tags, got, tt.want)
+			}
+			if tt.width >= cursorWidth+4 && got.width() > tt.width {
+				t.Errorf("row is %d cells wide, more than %d", got.width(), tt.width)
+			}
+		})
+	}
+}
+
+func TestTableLayoutRow(t *testing.T) {
+	l := tableLayout{num: 2, task: 8, due: 10, tags: 4}
+	got := l.row("> ", "3", "Call the bank", "2026-11-01", "[urgent]", "home")
+	if want := "> 3  Call th… 2026-11-01 home"; got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+	if len([]rune(got)) != l.width() {
+		t.Errorf("row is %d cells wide, want %d", len([]rune(got)), l.width())
+	}
+}
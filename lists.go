@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultList is the name of the list kept in the main todo file.
+const defaultList = "default"
+
+// listSet locates named todo lists. The default list is the main todo file;
+// every other list is a file of the same kind in a "lists" directory next
+// to it, e.g. lists/work.txt.
+type listSet struct {
+	backend     string
+	defaultPath string
+	lock        lockOptions
+}
+
+func newListSet(backend, defaultPath string, lock lockOptions) *listSet {
+	return &listSet{backend: backend, defaultPath: defaultPath, lock: lock}
+}
+
+func (ls *listSet) dir() string {
+	return filepath.Join(filepath.Dir(ls.defaultPath), "lists")
+}
+
+// path returns the todo file backing the named list.
+func (ls *listSet) path(name string) string {
+	if name == defaultList {
+		return ls.defaultPath
+	}
+	return filepath.Join(ls.dir(), name+filepath.Ext(ls.defaultPath))
+}
+
+// prefsPath returns the settings file shared by all lists, e.g.
+// todolist.settings.json next to todolist.txt.
+func (ls *listSet) prefsPath() string {
+	return strings.TrimSuffix(ls.defaultPath, filepath.Ext(ls.defaultPath)) + ".settings.json"
+}
+
+// prefs returns the saved settings for the named list.
+func (ls *listSet) prefs(name string) (listPrefs, error) {
+	p, err := loadPrefs(ls.prefsPath())
+	return p.Lists[name], err
+}
+
+// savePrefs records the settings for the named list.
+func (ls *listSet) savePrefs(name string, lp listPrefs) error {
+	p, err := loadPrefs(ls.prefsPath())
+	if err != nil {
+		return err
+	}
+	p.Lists[name] = lp
+	return savePrefs(ls.prefsPath(), p)
+}
+
+// names returns the default list followed by the others, sorted.
+func (ls *listSet) names() ([]string, error) {
+	entries, err := os.ReadDir(ls.dir())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	ext := filepath.Ext(ls.defaultPath)
+	seen := make(map[string]bool)
+	var names []string
+	for _, e := range entries {
+		n := e.Name()
+		if e.IsDir() || (filepath.Ext(n) != ext && filepath.Ext(n) != ".db") {
+			continue
+		}
+		n = strings.TrimSuffix(n, filepath.Ext(n))
+		if n != defaultList && !seen[n] {
+			seen[n] = true
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{defaultList}, names...), nil
+}
+
+// open opens the store for the named list, creating the list if needed.
+func (ls *listSet) open(name string) (Store, error) {
+	if err := validListName(name); err != nil {
+		return nil, err
+	}
+	path := ls.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return openStore(ls.backend, path, ls.lock)
+}
+
+func validListName(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return errors.New("list name is empty")
+	case strings.ContainsAny(name, `/\:`) || name == "." || name == "..":
+		return fmt.Errorf("invalid list name %q", name)
+	}
+	return nil
+}
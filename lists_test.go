@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newListModel returns a model on the default list of a fresh list set
+// holding todos.
+func newListModel(t *testing.T, todos ...Todo) (model, *listSet) {
+	t.Helper()
+	ls := newListSet("file", filepath.Join(t.TempDir(), "todolist.txt"), lockOptions{})
+	store, err := ls.open(defaultList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(todos); err != nil {
+		t.Fatal(err)
+	}
+	m := initialModel(store)
+	m.lists = ls
+	t.Cleanup(func() { m.store.Close() })
+	return m, ls
+}
+
+// listTexts returns what the named list holds on disk.
+func listTexts(t *testing.T, ls *listSet, name string) []string {
+	t.Helper()
+	todos, _, _, err := loadTodos(ls.path(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return todoTexts(todos)
+}
+
+func TestMoveTodo(t *testing.T) {
+	m, ls := newListModel(t, newTodo("Pay rent"), newTodo("Write report"))
+	check := func(step string, def, work []string) {
+		t.Helper()
+		if got := listTexts(t, ls, defaultList); !slices.Equal(got, def) {
+			t.Errorf("after %s, default list holds %q, want %q", step, got, def)
+		}
+		if got := listTexts(t, ls, "work"); !slices.Equal(got, work) {
+			t.Errorf("after %s, work list holds %q, want %q", step, got, work)
+		}
+	}
+
+	m = press(m, "j", "m", "n", "work", "enter")
+	if want := `Moved todo to list "work".`; m.status != want {
+		t.Errorf("status %q, want %q", m.status, want)
+	}
+	check("move", []string{"[ ] Pay rent"}, []string{"[ ] Write report"})
+	if got := todoTexts(m.todos); !slices.Equal(got, []string{"[ ] Pay rent"}) {
+		t.Errorf("list shows %q after the move", got)
+	}
+
+	m = press(m, "u")
+	check("undo", []string{"[ ] Pay rent", "[ ] Write report"}, nil)
+	m = press(m, "ctrl+r")
+	check("redo", []string{"[ ] Pay rent"}, []string{"[ ] Write report"})
+
+	// The picker lists the new list, and moving into the current one is
+	// refused.
+	m = press(m, "m")
+	if !slices.Equal(m.listNames, []string{defaultList, "work"}) {
+		t.Errorf("picker shows %q", m.listNames)
+	}
+	m = press(m, "enter")
+	if !strings.Contains(m.status, "already in list") {
+		t.Errorf("moving into the same list: status %q", m.status)
+	}
+
+	m = press(m, "m", "j", "enter")
+	check("second move", nil, []string{"[ ] Write report", "[ ] Pay rent"})
+
+	m = press(m, "L", "j", "enter")
+	if m.listName != "work" || !slices.Equal(todoTexts(m.todos), []string{"[ ] Write report", "[ ] Pay rent"}) {
+		t.Errorf("switched to %q showing %q", m.listName, todoTexts(m.todos))
+	}
+	if m = press(m, "u"); m.status != "Nothing to undo." {
+		t.Errorf("undo after switching lists: status %q", m.status)
+	}
+}
+
+// readOnlyStore is a memoryStore opened read-only.
+type readOnlyStore struct{ *memoryStore }
+
+func (readOnlyStore) ReadOnly() bool { return true }
+
+func TestMoveTodoRefused(t *testing.T) {
+	m, ls := newListModel(t, newTodo("Pay rent"))
+	m.store.Close()
+
+	m.store = readOnlyStore{newMemoryStore(newTodo("Pay rent"))}
+	m = press(m, "m", "n", "work", "enter")
+	if !strings.Contains(m.status, "read-only") {
+		t.Errorf("move from a read-only list: status %q", m.status)
+	}
+
+	failing := &failingStore{memoryStore: newMemoryStore(newTodo("Pay rent")), err: errors.New("disk full")}
+	m.store = failing
+	m = press(m, "m", "n", "work", "enter")
+	if !strings.HasPrefix(m.status, "Could not move") {
+		t.Errorf("move when this list cannot be saved: status %q", m.status)
+	}
+	m = press(m, " ", "m", "n", "work", "enter")
+	if !strings.Contains(m.status, "Press R to retry") {
+		t.Errorf("move with a failed save: status %q", m.status)
+	}
+	if got := listTexts(t, ls, "work"); len(got) > 0 {
+		t.Errorf("work list holds %q after refused moves", got)
+	}
+	if len(m.todos) != 1 || len(failing.todos) != 1 {
+		t.Errorf("the todo left its list: shown %d, stored %d", len(m.todos), len(failing.todos))
+	}
+}
+
+func TestMoveTodoFailure(t *testing.T) {
+	m, ls := newListModel(t, newTodo("Pay rent"))
+
+	// A directory where the target list's file should be makes adding to
+	// it fail, so the todo has to stay where it was.
+	if err := os.MkdirAll(ls.path("work"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	m = press(m, "m", "n", "work", "enter")
+	if !strings.HasPrefix(m.status, "Could not move") {
+		t.Errorf("status %q", m.status)
+	}
+	if got := listTexts(t, ls, defaultList); !slices.Equal(got, []string{"[ ] Pay rent"}) {
+		t.Errorf("default list holds %q after a failed move", got)
+	}
+	if got := todoTexts(m.todos); !slices.Equal(got, []string{"[ ] Pay rent"}) {
+		t.Errorf("list shows %q after a failed move", got)
+	}
+}
+
+func TestListNames(t *testing.T) {
+	ls := newListSet("file", filepath.Join(t.TempDir(), "todolist.txt"), lockOptions{})
+	for _, name := range []string{"work", "Home", "work"} {
+		s, err := ls.open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Save(nil); err != nil {
+			t.Fatal(err)
+		}
+		s.Close()
+	}
+	os.WriteFile(filepath.Join(ls.dir(), "notes.md"), nil, 0o644)
+	names, err := ls.names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{defaultList, "Home", "work"}; !slices.Equal(names, want) {
+		t.Errorf("names() = %q, want %q", names, want)
+	}
+
+	for _, name := range []string{"", " ", "a/b", `a\b`, "c:", ".", ".."} {
+		if _, err := ls.open(name); err == nil {
+			t.Errorf("opened a list named %q", name)
+		}
+	}
+}
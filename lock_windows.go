@@ -0,0 +1,40 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile takes an exclusive LockFileEx lock on f. Without wait it fails
+// with errLocked if another process holds the lock.
+func lockFile(f *os.File, wait bool) error {
+	flags := uint32(windows.LOCKFILE_EXCLUSIVE_LOCK)
+	if !wait {
+		flags |= windows.LOCKFILE_FAIL_IMMEDIATELY
+	}
+	err := windows.LockFileEx(windows.Handle(f.Fd()), flags, 0, 1, 0, lockRegion())
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errLocked
+	}
+	return err
+}
+
+func unlockFile(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, lockRegion())
+}
+
+// lockRegion is the byte that is locked: one far past the end of the file,
+// as Windows locks also stop others reading the locked bytes, and the
+// holder is written at the start.
+func lockRegion() *windows.Overlapped {
+	return &windows.Overlapped{OffsetHigh: 1 << 30}
+}
+
+// syncDir is a no-op: Windows renames don't need a directory sync.
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Markdown files hold todos as GitHub-style task lists, with subtasks
+// nested under their parents:
+//
+//	## home
+//
+//	- [ ] Pay rent (due 2024-06-01) !urgent #home <!-- 01J9Z... -->
+//	  - [x] Find the bank details #home <!-- 01J9Z... -->
+//
+// The due date, a priority other than medium and the tags are written
+// inline, and the ID goes in an HTML comment so it does not show when the
+// file is rendered but lets a re-import update the same todos. On import,
+// headings and anything that is not a task list item are skipped.
+
+var (
+	mdItem = regexp.MustCompile(`^(\s*)[-*+] \[([ xX])\] (.*)$`)
+	mdID   = regexp.MustCompile(`\s*<!--\s*(\S+)\s*-->\s*$`)
+	mdDue  = regexp.MustCompile(`\s*\(due (\d{4}-\d{2}-\d{2})\)`)
+)
+
+// markdownGroups are the ways the markdown export can group todos.
+var markdownGroups = []string{"none", "tag", "priority"}
+
+func writeMarkdown(w io.Writer, todos []Todo, opts exportOptions) error {
+	bw := bufio.NewWriter(w)
+
+	// Each top-level todo and its subtasks form a block, which goes under
+	// the heading for the top-level todo's first tag or its priority.
+	rows, depths := buildTree(todos, sortNone, false, nil)
+	type block struct{ rows, depths []int }
+	var blocks []block
+	for r, i := range rows {
+		if depths[r] == 0 {
+			blocks = append(blocks, block{})
+		}
+		b := &blocks[len(blocks)-1]
+		b.rows = append(b.rows, i)
+		b.depths = append(b.depths, depths[r])
+	}
+
+	var headings []string
+	groups := map[string][]block{}
+	for _, b := range blocks {
+		t := todos[b.rows[0]]
+		heading := ""
+		switch opts.group {
+		case "tag":
+			heading = "Untagged"
+			if len(t.Tags) > 0 {
+				heading = t.Tags[0]
+			}
+		case "priority":
+			heading = "Medium"
+			if t.Priority != "" {
+				heading = strings.ToUpper(t.Priority[:1]) + t.Priority[1:]
+			}
+		}
+		if _, ok := groups[heading]; !ok {
+			headings = append(headings, heading)
+		}
+		groups[heading] = append(groups[heading], b)
+	}
+	switch opts.group {
+	case "tag":
+		sortHeadings(headings, func(h string) int { return boolRank(h == "Untagged") })
+	case "priority":
+		sortHeadings(headings, func(h string) int { return priorityRank(strings.ToLower(h)) })
+	}
+
+	for n, heading := range headings {
+		if n > 0 {
+			bw.WriteString("\n")
+		}
+		if heading != "" {
+			fmt.Fprintf(bw, "## %s\n\n", heading)
+		}
+		for _, b := range groups[heading] {
+			for k, i := range b.rows {
+				bw.WriteString(strings.Repeat("  ", b.depths[k]) + formatMarkdownItem(todos[i]) + "\n")
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// sortHeadings orders headings by rank, then alphabetically.
+func sortHeadings(headings []string, rank func(string) int) {
+	sort.Slice(headings, func(i, j int) bool {
+		a, b := headings[i], headings[j]
+		if rank(a) != rank(b) {
+			return rank(a) < rank(b)
+		}
+		return strings.ToLower(a) < strings.ToLower(b)
+	})
+}
+
+func formatMarkdownItem(t Todo) string {
+	check := " "
+	if t.Done {
+		check = "x"
+	}
+	parts := []string{"- [" + check + "]", strings.Join(strings.Fields(t.Text), " ")}
+	if t.DueDate != "" {
+		parts = append(parts, "(due "+t.DueDate+")")
+	}
+	if t.Priority != "medium" {
+		parts = append(parts, "!"+t.Priority)
+	}
+	// A tag ends at a space, so spaces inside one become dashes and do
+	// not come back on import.
+	for _, tag := range t.Tags {
+		if tag = strings.Join(strings.Fields(tag), "-"); tag != "" {
+			parts = append(parts, "#"+tag)
+		}
+	}
+	if t.ID != "" {
+		parts = append(parts, "<!-- "+t.ID+" -->")
+	}
+	return strings.Join(parts, " ")
+}
+
+func readMarkdown(r io.Reader, _ importOptions) ([]Todo, []badLine, error) {
+	var todos []Todo
+	// open holds the indentation and ID of each item the next one could be
+	// nested under, outermost first.
+	type level struct {
+		indent int
+		id     string
+	}
+	var open []level
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		m := mdItem.FindStringSubmatch(sc.Text())
+		if m == nil {
+			if strings.TrimSpace(sc.Text()) != "" && !strings.HasPrefix(sc.Text(), " ") {
+				open = nil // a heading or paragraph ends the list
+			}
+			continue
+		}
+		indent := len(strings.ReplaceAll(m[1], "\t", "    "))
+		t := parseMarkdownItem(m[3])
+		t.Done = m[2] != " "
+		if t.Text == "" {
+			continue
+		}
+		if t.ID == "" {
+			t.ID = newID()
+		}
+		for len(open) > 0 && open[len(open)-1].indent >= indent {
+			open = open[:len(open)-1]
+		}
+		if len(open) > 0 {
+			t.ParentID = open[len(open)-1].id
+		}
+		open = append(open, level{indent, t.ID})
+		todos = append(todos, t)
+	}
+	return todos, nil, sc.Err()
+}
+
+// parseMarkdownItem reads the text of a task list item after the checkbox.
+func parseMarkdownItem(s string) Todo {
+	t := Todo{Priority: "medium"}
+	if m := mdID.FindStringSubmatch(s); m != nil {
+		t.ID = m[1]
+		s = s[:len(s)-len(m[0])]
+	}
+	if m := mdDue.FindStringSubmatch(s); m != nil {
+		t.DueDate = m[1]
+		s = strings.Replace(s, m[0], "", 1)
+	}
+	var text []string
+	for _, w := range strings.Fields(s) {
+		if len(w) > 1 && w[0] == '!' {
+			if p, ok := priorityWords[strings.ToLower(w[1:])]; ok {
+				t.Priority = p
+				continue
+			}
+		}
+		if len(w) > 1 && w[0] == '#' && !isIssueNumber(w) {
+			t.Tags = append(t.Tags, w[1:])
+			continue
+		}
+		text = append(text, w)
+	}
+	t.Text = strings.Join(text, " ")
+	return t
+}
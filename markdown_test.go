@@ -0,0 +1,99 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	todos := sampleTodos()
+	todos[1].Tags = []string{"home", "to read"}
+	// Markdown has nowhere to keep a repeat, and tags cannot hold spaces.
+	want := sampleTodos()
+	want[1].Tags = []string{"home", "to-read"}
+	for i := range want {
+		want[i].Recurrence = ""
+	}
+	for _, group := range markdownGroups {
+		t.Run(group, func(t *testing.T) {
+			out, got := roundTrip(t, "markdown", todos, exportOptions{group: group})
+			slices.SortFunc(got, func(a, b Todo) int { return strings.Compare(a.ID, b.ID) })
+			if !slices.Equal(summaries(got), summaries(want)) {
+				t.Errorf("got\n%s\nwant\n%s\nfrom\n%s", strings.Join(summaries(got), "\n"), strings.Join(summaries(want), "\n"), out)
+			}
+		})
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	tests := []struct {
+		group string
+		want  string
+	}{
+		{"none", `- [ ] Pay rent (due 2026-11-01) !urgent #home <!-- 01JA00000000000000000000A1 -->
+  - [ ] Call the bank #home #phone <!-- 01JA00000000000000000000A2 -->
+- [x] File taxes !low <!-- 01JA00000000000000000000A3 -->
+- [ ] Water plants (due 2026-10-20) <!-- 01JA00000000000000000000A4 -->
+`},
+		{"priority", `## Urgent
+
+- [ ] Pay rent (due 2026-11-01) !urgent #home <!-- 01JA00000000000000000000A1 -->
+  - [ ] Call the bank #home #phone <!-- 01JA00000000000000000000A2 -->
+
+## Medium
+
+- [ ] Water plants (due 2026-10-20) <!-- 01JA00000000000000000000A4 -->
+
+## Low
+
+- [x] File taxes !low <!-- 01JA00000000000000000000A3 -->
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.group, func(t *testing.T) {
+			var b strings.Builder
+			if err := writeMarkdown(&b, sampleTodos(), exportOptions{group: tt.group}); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMarkdown(t *testing.T) {
+	input := `# Plans
+
+Some text with - [ ] inline brackets.
+
+- [ ] Trip !high #travel <!-- 01JA00000000000000000000C1 -->
+  - [X] Book flights (due 2026-12-01)
+	- [ ] Pick seats
+  * [ ] Pack #travel
+- Not a task
++ [ ] Fix #12 and #bug
+
+## Later
+
+  - [ ] Indented after a heading
+- [ ]
+`
+	got, _ := readString(t, "markdown", input)
+	if len(got) != 6 {
+		t.Fatalf("got %d todos, want 6: %v", len(got), summaries(got))
+	}
+	id := func(i int) string { return got[i].ID }
+	want := []string{
+		`01JA00000000000000000000C1 [ ] "Trip" urgent due= tags=[travel] parent= rec=`,
+		id(1) + ` [x] "Book flights" medium due=2026-12-01 tags=[] parent=01JA00000000000000000000C1 rec=`,
+		id(2) + ` [ ] "Pick seats" medium due= tags=[] parent=` + id(1) + ` rec=`,
+		id(3) + ` [ ] "Pack" medium due= tags=[travel] parent=01JA00000000000000000000C1 rec=`,
+		id(4) + ` [ ] "Fix #12 and" medium due= tags=[bug] parent= rec=`,
+		id(5) + ` [ ] "Indented after a heading" medium due= tags=[] parent= rec=`,
+	}
+	if !slices.Equal(summaries(got), want) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(summaries(got), "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The todo file starts with a header record naming its format version:
+//
+//	{"format":"go-do-it","version":2}
+//
+// followed by one JSON todo per line. Files without a header predate
+// versioning and are treated as version 0, which may mix legacy text lines
+// with version 1 JSON lines.
+const (
+	formatName    = "go-do-it"
+	formatVersion = 2
+)
+
+type fileHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+}
+
+// record is one non-empty line of a todo file.
+type record struct {
+	line int
+	text string
+}
+
+// badLine is a line that could not be migrated or decoded.
+type badLine struct {
+	line int
+	text string
+	err  error
+}
+
+// skippedLinesError reports lines that Load could not read. It comes back
+// together with the todos that were read fine.
+type skippedLinesError struct {
+	path     string
+	lines    []badLine
+	rejected string
+}
+
+func (e *skippedLinesError) Error() string {
+	msg := fmt.Sprintf("skipped %d unreadable line(s) in %s (first: line %d: %v)",
+		len(e.lines), e.path, e.lines[0].line, e.lines[0].err)
+	if e.rejected != "" {
+		msg += fmt.Sprintf("; moved to %s", e.rejected)
+	}
+	return msg
+}
+
+// migrations[v] upgrades records from format version v to v+1.
+var migrations = []func(records []record) ([]record, []badLine){
+	migrateLegacyText,
+	migrateAddIDs,
+}
+
+// migrateLegacyText turns legacy text lines into version 1 JSON. Lines that
+// already look like JSON are passed through for the next step to check.
+func migrateLegacyText(records []record) ([]record, []badLine) {
+	var out []record
+	var bad []badLine
+	for _, r := range records {
+		if strings.HasPrefix(r.text, "{") {
+			out = append(out, r)
+			continue
+		}
+		b, err := json.Marshal(parseLegacyTodo(r.text))
+		if err != nil {
+			bad = append(bad, badLine{r.line, r.text, err})
+			continue
+		}
+		out = append(out, record{r.line, string(b)})
+	}
+	return out, bad
+}
+
+// migrateAddIDs gives version 1 todos an ID and timestamps.
+func migrateAddIDs(records []record) ([]record, []badLine) {
+	var out []record
+	var bad []badLine
+	for _, r := range records {
+		var t Todo
+		if err := json.Unmarshal([]byte(r.text), &t); err != nil {
+			bad = append(bad, badLine{r.line, r.text, err})
+			continue
+		}
+		todos := []Todo{t}
+		backfillTodos(todos)
+		b, err := json.Marshal(todos[0])
+		if err != nil {
+			bad = append(bad, badLine{r.line, r.text, err})
+			continue
+		}
+		out = append(out, record{r.line, string(b)})
+	}
+	return out, bad
+}
+
+// readRecords reads the non-empty lines of a todo file and its format
+// version, taken from the header if there is one.
+func readRecords(path string) (int, []record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+
+	version := 0
+	var records []record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if len(records) == 0 && version == 0 {
+			var h fileHeader
+			if json.Unmarshal([]byte(line), &h) == nil && h.Format == formatName {
+				version = h.Version
+				continue
+			}
+		}
+		records = append(records, record{n, line})
+	}
+	return version, records, scanner.Err()
+}
+
+// decodeRecords upgrades records from version to formatVersion and decodes
+// them into todos.
+func decodeRecords(version int, records []record) ([]Todo, []badLine, error) {
+	if version > formatVersion {
+		return nil, nil, fmt.Errorf("file format version %d is newer than this go-do-it understands (%d)", version, formatVersion)
+	}
+	var skipped []badLine
+	for v := version; v < formatVersion; v++ {
+		var bad []badLine
+		records, bad = migrations[v](records)
+		skipped = append(skipped, bad...)
+	}
+	todos := []Todo{}
+	for _, r := range records {
+		var t Todo
+		if err := json.Unmarshal([]byte(r.text), &t); err != nil {
+			skipped = append(skipped, badLine{r.line, r.text, err})
+			continue
+		}
+		todos = append(todos, t)
+	}
+	return todos, skipped, nil
+}
+
+// writeRejected appends lines that could not be read to path, so they are
+// not lost when the todo file is rewritten.
+func writeRejected(path string, lines []badLine) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, l := range lines {
+		fmt.Fprintf(w, "%s\n", l.text)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// legacyFile mixes what old versions wrote: text lines from before JSON,
+// version 1 JSON without IDs, and a line neither can read.
+const legacyFile = `[x] [urgent] Pay rent @2024-06-01
+[ ] Call mum
+
+{"Text":"Water plants","Priority":"low","DueDate":"","Done":false,"Tags":["home"]}
+{"Text": broken
+Buy stamps [low]
+`
+
+func TestLoadLegacyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todolist.txt")
+	if err := os.WriteFile(path, []byte(legacyFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := openFileStore(path, lockOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	todos, err := s.Load()
+	var skipped *skippedLinesError
+	if !errors.As(err, &skipped) || len(skipped.lines) != 1 || skipped.lines[0].line != 5 {
+		t.Fatalf("Load error %v, want line 5 skipped", err)
+	}
+	want := []Todo{
+		{Text: "Pay rent", Priority: "urgent", DueDate: "2024-06-01", Done: true},
+		{Text: "Call mum", Priority: "medium"},
+		{Text: "Water plants", Priority: "low", Tags: []string{"home"}},
+		{Text: "Buy stamps", Priority: "low"},
+	}
+	if len(todos) != len(want) {
+		t.Fatalf("loaded %q, want %d todos", todoTexts(todos), len(want))
+	}
+	for i, got := range todos {
+		w := want[i]
+		if got.Text != w.Text || got.Priority != w.Priority || got.DueDate != w.DueDate || got.Done != w.Done || !slices.Equal(got.Tags, w.Tags) {
+			t.Errorf("todo %d = %+v, want %+v", i, got, w)
+		}
+		if got.ID == "" || got.CreatedAt.IsZero() || got.UpdatedAt.IsZero() || got.Done == got.CompletedAt.IsZero() {
+			t.Errorf("todo %d was not given an ID and timestamps: %+v", i, got)
+		}
+	}
+
+	// The upgrade is written back at once, so the IDs stick, and the line
+	// that could not be read is kept aside.
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), `{"format":"go-do-it","version":2}`) {
+		t.Errorf("file was not rewritten in the current format:\n%s", data)
+	}
+	rejected, _ := os.ReadFile(path + ".rejected")
+	if string(rejected) != "{\"Text\": broken\n" {
+		t.Errorf("rejected file holds %q", rejected)
+	}
+	again, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range again {
+		if again[i].ID != todos[i].ID {
+			t.Errorf("todo %d changed ID from %s to %s on the second load", i, todos[i].ID, again[i].ID)
+		}
+	}
+}
+
+func TestLoadLegacyFileReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todolist.txt")
+	if err := os.WriteFile(path, []byte(legacyFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	holder, err := openFileStore(path, lockOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer holder.Close()
+	s, err := openFileStore(path, lockOptions{onLocked: lockReadOnly})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if !s.ReadOnly() {
+		t.Fatal("second store was not opened read-only")
+	}
+
+	// A read-only store upgrades in memory only and leaves the file alone.
+	if _, err := s.Load(); err == nil {
+		t.Error("skipped line was not reported")
+	}
+	if data, _ := os.ReadFile(path); string(data) != legacyFile {
+		t.Errorf("read-only load rewrote the file:\n%s", data)
+	}
+	if _, err := os.Stat(path + ".rejected"); !os.IsNotExist(err) {
+		t.Errorf("read-only load wrote a rejected file: %v", err)
+	}
+}
+
+func TestLoadNewerFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todolist.txt")
+	data := "{\"format\":\"go-do-it\",\"version\":3}\n{\"Text\":\"Pay rent\"}\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := loadTodos(path); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("loading a newer format: %v", err)
+	}
+}
+
+func TestModelWarnsAboutSkippedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todolist.txt")
+	if err := os.WriteFile(path, []byte(legacyFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := openFileStore(path, lockOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	m := initialModel(s)
+	if !strings.HasPrefix(m.status, "Warning: skipped 1 unreadable line(s)") || len(m.todos) != 4 {
+		t.Errorf("status %q with %d todos", m.status, len(m.todos))
+	}
+}
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/charmbracelet/bubbles/textinput"
 )
 
@@ -12,6 +16,15 @@ func newTextInputModel() textinput.Model {
 	return ti
 }
 
+func newSearchInputModel() textinput.Model {
+	ti := textinput.New()
+	ti.Prompt = "/"
+	ti.Placeholder = "Search todos..."
+	ti.CharLimit = 100
+	ti.Width = 30
+	return ti
+}
+
 type mode int
 
 const (
@@ -22,19 +35,62 @@ const (
 	modeEdit
 	modeHelp
 	modeTagSearch
+	modeLists
+	modeSearch
+	modeDetail
+)
+
+// pendingRetry is a store operation that failed and can be retried with R.
+type pendingRetry int
+
+const (
+	retryNone pendingRetry = iota
+	retryLoad
+	retrySave
 )
 
+// Todo is a single item. ID stays the same for the todo's whole life,
+// whatever happens to its position in the list.
 type Todo struct {
-	Text     string
-	Priority string
-	DueDate  string
-	Done     bool
-	Tags     []string
+	ID          string
+	Text        string
+	Priority    string
+	DueDate     string
+	Done        bool
+	Tags        []string
+	ParentID    string `json:",omitempty"`
+	Recurrence  string `json:",omitempty"` // RRULE, e.g. FREQ=WEEKLY;BYDAY=MO
+	Notes       string `json:",omitempty"` // free-form, may span several lines
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt time.Time `json:",omitzero"`
+}
+
+// newTodo returns a todo with a fresh ID, created now.
+func newTodo(text string) Todo {
+	now := time.Now()
+	return Todo{ID: newID(), Text: text, Priority: "medium", CreatedAt: now, UpdatedAt: now}
+}
+
+// setDone marks the todo done or not done, tracking when it was completed.
+func (t *Todo) setDone(done bool) {
+	t.Done = done
+	t.UpdatedAt = time.Now()
+	if done {
+		t.CompletedAt = t.UpdatedAt
+	} else {
+		t.CompletedAt = time.Time{}
+	}
 }
 
 type model struct {
+	store          Store
 	todos          []Todo
-	cursor         int
+	rows           []int // indexes into todos of the rows shown, in order
+	depths         []int // subtask depth of each row
+	collapsed      map[string]bool
+	cursor         int // index into rows
+	offset         int // first row shown on screen; see visibleOffset
 	mode           mode
 	textInput      textinput.Model
 	status         string
@@ -42,46 +98,232 @@ type model struct {
 	height         int
 	confirmIdx     int
 	editIdx        int
+	detailScroll   int  // lines of the detail view scrolled past
+	showDetail     bool // the detail pane is shown next to the table
 	priorityInput  int
 	prioritySelect bool
 	dueDateInput   string
 	dueDateSelect  bool
 	tagsInput      string
 	tagsSelect     bool
+	recurInput     string
+	recurSelect    bool
 	tempTodoText   string
+	addParent      string
 	tagSearchInput textinput.Model
+	tagSearchAny   bool
+	filter         tagFilter
+	searchInput    textinput.Model
+	search         string
+	sortKey        sortKey
+	sortDesc       bool
+
+	history history
 
-	lastDeletedTodo  Todo
-	lastDeletedIndex int
-	canUndo          bool
+	pending   pendingRetry
+	quitArmed bool
+
+	lists      *listSet
+	listName   string
+	listNames  []string
+	listCursor int
+	listMove   bool
+	listNew    bool
 }
 
-func initialModel() model {
+func initialModel(store Store) model {
 	ti := textinput.New()
 	ti.Placeholder = "Type a todo and press Enter"
 	ti.CharLimit = 256
 	ti.Width = 50
 
-	return model{
-		todos:            loadTodos(),
-		cursor:           0,
-		mode:             modeView,
-		textInput:        ti,
-		status:           "Welcome to Go-Do-It! Press 'a' to add a todo.",
-		width:            0,
-		height:           0,
-		confirmIdx:       -1,
-		editIdx:          -1,
-		priorityInput:    1,
-		prioritySelect:   false,
-		dueDateInput:     "",
-		dueDateSelect:    false,
-		tagsInput:        "",
-		tagsSelect:       false,
-		tagSearchInput:   newTextInputModel(),
-		lastDeletedTodo:  Todo{},
-		lastDeletedIndex: -1,
-		canUndo:          false,
+	m := model{
+		store:          store,
+		todos:          []Todo{},
+		collapsed:      map[string]bool{},
+		cursor:         0,
+		mode:           modeView,
+		textInput:      ti,
+		status:         "Welcome to Go-Do-It! Press 'a' to add a todo.",
+		width:          0,
+		height:         0,
+		confirmIdx:     -1,
+		editIdx:        -1,
+		priorityInput:  1,
+		prioritySelect: false,
+		dueDateInput:   "",
+		dueDateSelect:  false,
+		tagsInput:      "",
+		tagsSelect:     false,
+		tagSearchInput: newTextInputModel(),
+		searchInput:    newSearchInputModel(),
+		listName:       defaultList,
+	}
+	if m.load() {
+		if m.readOnly() {
+			m.status = "Another go-do-it has this list open — viewing read-only."
+		}
+	}
+	return m
+}
+
+// readOnly reports whether the list was opened without its lock because
+// another go-do-it has it open.
+func (m *model) readOnly() bool {
+	ro, ok := m.store.(interface{ ReadOnly() bool })
+	return ok && ro.ReadOnly()
+}
+
+// load replaces the in-memory list with the stored one, forgetting the undo
+// history, whose steps were taken on the list it replaces. If loading fails
+// the current list is kept and the error is shown with a retry hint. It
+// returns false whenever it left a message in the status line.
+func (m *model) load() bool {
+	todos, err := m.store.Load()
+	var skipped *skippedLinesError
+	if err != nil && !errors.As(err, &skipped) {
+		m.pending = retryLoad
+		m.status = fmt.Sprintf("Could not load todos: %v. Press R to retry.", err)
+		return false
 	}
+	m.todos = todos
+	m.history = history{}
+	m.pending = retryNone
+	m.refresh()
+	if skipped != nil {
+		m.status = "Warning: " + skipped.Error()
+		return false
+	}
+	return true
 }
 
+// refresh rebuilds the visible rows after the todos or the filter changed.
+// The cursor stays on the todo it was on if that is still shown.
+func (m *model) refresh() {
+	keep := ""
+	if m.cursor < len(m.rows) && m.rows[m.cursor] < len(m.todos) {
+		keep = m.todos[m.rows[m.cursor]].ID
+	}
+	live := ""
+	if m.mode == modeSearch {
+		live = m.searchPattern()
+	}
+	if !m.filter.active() && live == "" {
+		m.rows, m.depths = buildTree(m.todos, m.sortKey, m.sortDesc, m.collapsed)
+		m.focus(keep)
+		return
+	}
+	// Filtered rows are shown flat, since a match's parent may not match.
+	rows := make([]int, 0, len(m.todos))
+	for i, t := range m.todos {
+		if !m.filter.matches(t) {
+			continue
+		}
+		if _, ok := searchTodo(t, live); live != "" && !ok {
+			continue
+		}
+		rows = append(rows, i)
+	}
+	sortRows(rows, m.todos, m.sortKey, m.sortDesc)
+	m.rows = rows
+	m.depths = make([]int, len(rows))
+	m.focus(keep)
+}
+
+// reveal expands the parents of the todo with id, so that it is shown, and
+// moves the cursor to it.
+func (m *model) reveal(id string) {
+	parents := make(map[string]string, len(m.todos))
+	for _, t := range m.todos {
+		parents[t.ID] = t.ParentID
+	}
+	for p, n := parents[id], 0; p != "" && n < len(m.todos); p, n = parents[p], n+1 {
+		delete(m.collapsed, p)
+	}
+	m.refresh()
+	m.focus(id)
+}
+
+// focus moves the cursor to the row showing the todo with id, or just keeps
+// it in range if that todo is not shown.
+func (m *model) focus(id string) {
+	for r, i := range m.rows {
+		if m.todos[i].ID == id {
+			m.cursor = r
+			return
+		}
+	}
+	if m.cursor >= len(m.rows) {
+		m.cursor = max(len(m.rows)-1, 0)
+	}
+}
+
+// selected returns the index in m.todos of the todo under the cursor.
+func (m *model) selected() (int, bool) {
+	if m.cursor < len(m.rows) {
+		return m.rows[m.cursor], true
+	}
+	return -1, false
+}
+
+// loadPrefs applies the saved sort order of the current list.
+func (m *model) loadPrefs() {
+	if m.lists == nil {
+		return
+	}
+	lp, err := m.lists.prefs(m.listName)
+	if err != nil {
+		m.status = fmt.Sprintf("Could not read settings: %v", err)
+	}
+	m.sortKey = parseSortKey(lp.Sort)
+	m.sortDesc = lp.Desc
+	m.refresh()
+}
+
+// savePrefs remembers the sort order of the current list.
+func (m *model) savePrefs() {
+	if m.lists == nil {
+		return
+	}
+	lp := listPrefs{Sort: m.sortKey.String(), Desc: m.sortDesc}
+	if err := m.lists.savePrefs(m.listName, lp); err != nil {
+		m.status = fmt.Sprintf("Could not save settings: %v", err)
+	}
+}
+
+// persist runs a store operation for a change already applied to m.todos.
+// On failure the in-memory list is kept and the error is shown with a retry
+// hint; once a save has failed, later changes rewrite the whole list so
+// nothing from the failed one is lost.
+func (m *model) persist(op func() error) bool {
+	switch m.pending {
+	case retryLoad:
+		m.status = "Not saved: the list failed to load. Press R to retry loading."
+		return false
+	case retrySave:
+		op = func() error { return m.store.Save(m.todos) }
+	}
+	if err := op(); err != nil {
+		m.pending = retrySave
+		m.status = fmt.Sprintf("Save failed: %v. Press R to retry.", err)
+		return false
+	}
+	m.pending = retryNone
+	return true
+}
+
+// retry repeats the store operation that last failed.
+func (m *model) retry() {
+	switch m.pending {
+	case retryLoad:
+		if m.load() {
+			m.status = "Todos loaded."
+		}
+	case retrySave:
+		if m.persist(nil) {
+			m.status = "Todos saved."
+		}
+	default:
+		m.status = "Nothing to retry."
+	}
+}
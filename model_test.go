@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// key returns the message for a key as the program would send it. Any
+// string that is not a named key is typed as runes.
+func key(s string) tea.KeyMsg {
+	switch s {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	case "tab":
+		return tea.KeyMsg{Type: tea.KeyTab}
+	case " ":
+		return tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	case "ctrl+u":
+		return tea.KeyMsg{Type: tea.KeyCtrlU}
+	case "ctrl+r":
+		return tea.KeyMsg{Type: tea.KeyCtrlR}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// press sends keys to m one after another.
+func press(m model, keys ...string) model {
+	for _, k := range keys {
+		next, _ := m.Update(key(k))
+		m = next.(model)
+	}
+	return m
+}
+
+// sampleTodos is a small list covering what a store has to carry:
+// priorities, due dates, tags, a repeat, a subtask and a done todo.
+func sampleTodos() []Todo {
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, time.Local) }
+	return []Todo{
+		{ID: "01JA00000000000000000000A1", Text: "Pay rent", Priority: "urgent", DueDate: "2026-11-01", Tags: []string{"home"},
+			Recurrence: "FREQ=MONTHLY", CreatedAt: day(1), UpdatedAt: day(1)},
+		{ID: "01JA00000000000000000000A2", Text: "Call the bank", Priority: "medium", Tags: []string{"home", "phone"},
+			ParentID: "01JA00000000000000000000A1", CreatedAt: day(2), UpdatedAt: day(2)},
+		{ID: "01JA00000000000000000000A3", Text: "File taxes", Priority: "low", Done: true,
+			CreatedAt: day(3), UpdatedAt: day(10), CompletedAt: day(10)},
+		{ID: "01JA00000000000000000000A4", Text: "Water plants", Priority: "medium", DueDate: "2026-10-20",
+			Recurrence: "FREQ=WEEKLY;BYDAY=MO,TH", CreatedAt: day(4), UpdatedAt: day(4)},
+	}
+}
+
+// summary is what every store carries of a todo, for comparing lists.
+func summary(t Todo) string {
+	done := " "
+	if t.Done {
+		done = "x"
+	}
+	return fmt.Sprintf("%s [%s] %q %s due=%s tags=%v parent=%s rec=%s",
+		t.ID, done, t.Text, t.Priority, t.DueDate, t.Tags, t.ParentID, t.Recurrence)
+}
+
+func summaries(todos []Todo) []string {
+	out := make([]string, len(todos))
+	for i, t := range todos {
+		out[i] = summary(t)
+	}
+	return out
+}
+
+// todoTexts lists todos as "[x] text", for comparing lists.
+func todoTexts(todos []Todo) []string {
+	out := make([]string, len(todos))
+	for i, t := range todos {
+		done := " "
+		if t.Done {
+			done = "x"
+		}
+		out[i] = "[" + done + "] " + t.Text
+	}
+	return out
+}
+
+func TestModelChanges(t *testing.T) {
+	store := newMemoryStore(newTodo("Pay rent"), newTodo("Call the bank"))
+	m := initialModel(store)
+	check := func(step string, want ...string) {
+		t.Helper()
+		if got := todoTexts(store.todos); !slices.Equal(got, want) {
+			t.Errorf("after %s, stored %q, want %q", step, got, want)
+		}
+		if got := todoTexts(m.todos); !slices.Equal(got, want) {
+			t.Errorf("after %s, shown %q, want %q", step, got, want)
+		}
+	}
+
+	m = press(m, "a", "Buy stamps", "enter")
+	check("add", "[ ] Pay rent", "[ ] Call the bank", "[ ] Buy stamps")
+
+	m = press(m, "k", "k", " ")
+	check("toggle", "[x] Pay rent", "[ ] Call the bank", "[ ] Buy stamps")
+
+	m = press(m, "j", "d", "n")
+	check("cancelled delete", "[x] Pay rent", "[ ] Call the bank", "[ ] Buy stamps")
+	m = press(m, "d", "y")
+	check("delete", "[x] Pay rent", "[ ] Buy stamps")
+	m = press(m, "u")
+	check("undo", "[x] Pay rent", "[ ] Call the bank", "[ ] Buy stamps")
+
+	m = press(m, "e", "ctrl+u", "Call the bank again", "enter", "enter", "enter", "enter", "enter")
+	check("edit", "[x] Pay rent", "[ ] Call the bank again", "[ ] Buy stamps")
+
+	m = press(m, "a", "   ", "enter")
+	check("empty add", "[x] Pay rent", "[ ] Call the bank again", "[ ] Buy stamps")
+
+	m = press(m, "D", "y")
+	check("delete all")
+}
+
+// failingStore is a memoryStore whose writes, or also loads, fail while err
+// is set.
+type failingStore struct {
+	*memoryStore
+	err      error
+	failLoad bool
+}
+
+func (s *failingStore) Load() ([]Todo, error) {
+	if s.failLoad && s.err != nil {
+		return nil, s.err
+	}
+	return s.memoryStore.Load()
+}
+
+func (s *failingStore) Save(todos []Todo) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.memoryStore.Save(todos)
+}
+
+func (s *failingStore) Put(todo Todo) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.memoryStore.Put(todo)
+}
+
+func (s *failingStore) Delete(id string) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.memoryStore.Delete(id)
+}
+
+func TestModelSaveFailure(t *testing.T) {
+	store := &failingStore{memoryStore: newMemoryStore(newTodo("Pay rent"))}
+	m := initialModel(store)
+
+	store.err = errors.New("disk full")
+	m = press(m, "a", "Buy stamps", "enter")
+	if !strings.Contains(m.status, "Save failed: disk full") || m.pending != retrySave {
+		t.Fatalf("status %q, pending %v after a failed save", m.status, m.pending)
+	}
+	if got := todoTexts(m.todos); len(got) != 2 {
+		t.Errorf("the added todo was dropped from the list: %q", got)
+	}
+
+	m = press(m, "r")
+	if len(m.todos) != 2 || !strings.Contains(m.status, "unsaved changes") {
+		t.Errorf("reload with unsaved changes: status %q, %d todos", m.status, len(m.todos))
+	}
+	next, cmd := m.Update(key("q"))
+	m = next.(model)
+	if cmd != nil || !strings.Contains(m.status, "Press q again") {
+		t.Errorf("first q with unsaved changes quit, status %q", m.status)
+	}
+
+	m = press(m, "R")
+	if m.pending != retrySave {
+		t.Errorf("retry while the store still fails cleared pending")
+	}
+	store.err = nil
+	m = press(m, "R")
+	if m.pending != retryNone || m.status != "Todos saved." {
+		t.Errorf("status %q, pending %v after retrying", m.status, m.pending)
+	}
+	if got, want := todoTexts(store.todos), []string{"[ ] Pay rent", "[ ] Buy stamps"}; !slices.Equal(got, want) {
+		t.Errorf("stored %q, want %q", got, want)
+	}
+	m = press(m, "R")
+	if m.status != "Nothing to retry." {
+		t.Errorf("status %q with nothing to retry", m.status)
+	}
+}
+
+func TestModelLoadFailure(t *testing.T) {
+	store := &failingStore{memoryStore: newMemoryStore(newTodo("Pay rent")), err: errors.New("no such disk"), failLoad: true}
+	m := initialModel(store)
+	if m.pending != retryLoad || !strings.Contains(m.status, "Could not load todos: no such disk") {
+		t.Fatalf("status %q, pending %v after a failed load", m.status, m.pending)
+	}
+
+	// Saving now would overwrite the list that could not be read.
+	m = press(m, "a", "Buy stamps", "enter")
+	if len(store.todos) != 1 || !strings.Contains(m.status, "failed to load") {
+		t.Errorf("change saved over an unloaded list: status %q, stored %q", m.status, todoTexts(store.todos))
+	}
+
+	store.err = nil
+	m = press(m, "R")
+	if m.pending != retryNone || m.status != "Todos loaded." {
+		t.Errorf("status %q, pending %v after retrying", m.status, m.pending)
+	}
+	if got, want := todoTexts(m.todos), []string{"[ ] Pay rent"}; !slices.Equal(got, want) {
+		t.Errorf("loaded %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// notesEditedMsg comes back once the editor started by editNotes exits.
+type notesEditedMsg struct {
+	id   string // the todo whose notes were edited
+	path string // the temp file holding them
+	err  error
+}
+
+// editorCommand returns the command that opens path in the user's editor:
+// $VISUAL, then $EDITOR, then a platform default. Like git, it runs the
+// variable through the shell, so it may hold arguments, as in "code -w".
+func editorCommand(path string) *exec.Cmd {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		editor := strings.TrimSpace(os.Getenv(env))
+		if editor == "" {
+			continue
+		}
+		if runtime.GOOS == "windows" {
+			args := strings.Fields(editor)
+			return exec.Command(args[0], append(args[1:], path)...)
+		}
+		return exec.Command("sh", "-c", editor+` "$@"`, editor, path)
+	}
+	if runtime.GOOS == "windows" {
+		return exec.Command("notepad", path)
+	}
+	return exec.Command("vi", path)
+}
+
+// editNotes suspends the TUI and opens the notes of the todo at i in the
+// user's editor, in a temp file. The result arrives as a notesEditedMsg.
+func (m *model) editNotes(i int) tea.Cmd {
+	t := m.todos[i]
+	f, err := os.CreateTemp("", "go-do-it-notes-*.md")
+	if err != nil {
+		m.status = fmt.Sprintf("Could not create a file for the notes: %v", err)
+		return nil
+	}
+	_, err = f.WriteString(t.Notes)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		m.status = fmt.Sprintf("Could not write the notes: %v", err)
+		return nil
+	}
+	m.status = fmt.Sprintf("Editing notes of %s…", quoteText(t.Text))
+	return tea.ExecProcess(editorCommand(f.Name()), func(err error) tea.Msg {
+		return notesEditedMsg{id: t.ID, path: f.Name(), err: err}
+	})
+}
+
+// notesEdited saves the notes the editor left in msg.path.
+func (m *model) notesEdited(msg notesEditedMsg) {
+	defer os.Remove(msg.path)
+	if msg.err != nil {
+		m.status = fmt.Sprintf("Editor failed: %v. Notes unchanged.", msg.err)
+		return
+	}
+	b, err := os.ReadFile(msg.path)
+	if err != nil {
+		m.status = fmt.Sprintf("Could not read the notes back: %v", err)
+		return
+	}
+	i := indexOfTodo(m.todos, msg.id)
+	if i < 0 {
+		m.status = "The todo was removed while its notes were open."
+		return
+	}
+	t := m.todos[i]
+	notes := strings.TrimRight(strings.ReplaceAll(string(b), "\r\n", "\n"), " \t\n")
+	if notes == t.Notes {
+		m.status = "Notes unchanged."
+		return
+	}
+	t.Notes = notes
+	t.UpdatedAt = time.Now()
+	m.status = "Notes saved (press 'u' to undo)"
+	if notes == "" {
+		m.status = "Notes cleared (press 'u' to undo)"
+	}
+	m.exec(updateCmd("edit notes", m.todos[i], t))
+}
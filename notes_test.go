@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNotesRoundTrip(t *testing.T) {
+	notes := "Ask about the deposit; bring the lease.\nLandlord: Sam, 555-0100, \\ext 2"
+	for _, name := range []string{"ical", "csv", "taskwarrior"} {
+		t.Run(name, func(t *testing.T) {
+			todos := sampleTodos()
+			todos[0].Notes = notes
+			_, got := roundTrip(t, name, todos, exportOptions{})
+			if len(got) != len(todos) {
+				t.Fatalf("got %d todos, want %d", len(got), len(todos))
+			}
+			for i, td := range got {
+				if td.Notes != todos[i].Notes {
+					t.Errorf("todo %d: notes %q, want %q", i, td.Notes, todos[i].Notes)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// listPrefs are the view settings remembered for one list.
+type listPrefs struct {
+	Sort string `json:"sort,omitempty"`
+	Desc bool   `json:"desc,omitempty"`
+}
+
+// prefs holds per-list settings, keyed by list name.
+type prefs struct {
+	Lists map[string]listPrefs `json:"lists"`
+}
+
+func loadPrefs(path string) (prefs, error) {
+	p := prefs{Lists: map[string]listPrefs{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return p, err
+	}
+	if p.Lists == nil {
+		p.Lists = map[string]listPrefs{}
+	}
+	return p, nil
+}
+
+// savePrefs writes p atomically, like saveTodos does for todo files.
+func savePrefs(path string, p prefs) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
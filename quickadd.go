@@ -0,0 +1,192 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// quickAdd is a todo typed on one line, e.g. "Pay rent tomorrow !urgent
+// #home", split into its fields.
+type quickAdd struct {
+	text       string
+	due        string
+	priority   string
+	tags       []string
+	recurrence string
+}
+
+var priorityWords = map[string]string{
+	"urgent": "urgent", "high": "urgent", "1": "urgent",
+	"medium": "medium", "med": "medium", "2": "medium",
+	"low": "low", "3": "low",
+}
+
+// parseQuickAdd pulls the fields out of a one-line todo:
+//
+//   - !urgent, !medium or !low (also !high, !1, !2, !3) set the priority
+//   - #tag or +tag add a tag; #12 on its own is taken as an issue number
+//   - a due date at the end: YYYY-MM-DD, today, tomorrow, friday, next
+//     friday, next week, next month, in 3 days, in 2 weeks
+//   - a repeat at the end: every monday, every 2 weeks, every other day
+//
+// Priorities and tags may go anywhere, but dates and repeats only count at
+// the end of the line, so "Every day matters" keeps its words. Everything
+// else is the todo's text. A word starting with a backslash is kept as
+// text, without the backslash, so "\#1" stays "#1".
+func parseQuickAdd(s string, now time.Time) quickAdd {
+	q := quickAdd{priority: "medium"}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	var text []string
+	literal := 0 // text[:literal] ends with an escaped word
+	for _, w := range strings.Fields(s) {
+		switch {
+		case strings.HasPrefix(w, `\`) && len(w) > 1:
+			text = append(text, w[1:])
+			literal = len(text)
+			continue
+		case len(w) > 1 && w[0] == '!':
+			if p, ok := priorityWords[strings.ToLower(w[1:])]; ok {
+				q.priority = p
+				continue
+			}
+		case len(w) > 1 && (w[0] == '#' || w[0] == '+') && !isIssueNumber(w):
+			q.tags = append(q.tags, w[1:])
+			continue
+		}
+		text = append(text, w)
+	}
+
+	// Take a due date and a repeat off the end, in either order, trying
+	// the longest phrase first.
+	for found := true; found; {
+		found = false
+		for i := literal; i < len(text) && !found; i++ {
+			if q.due == "" {
+				if due, n := parseDuePhrase(text[i:], today); n == len(text)-i {
+					q.due = due.Format(dateLayout)
+					text, found = text[:i], true
+					continue
+				}
+			}
+			if q.recurrence == "" && strings.EqualFold(text[i], "every") {
+				if rule, n := parseEveryPhrase(text[i:]); n == len(text)-i {
+					q.recurrence = rule
+					text, found = text[:i], true
+				}
+			}
+		}
+	}
+	q.text = strings.Join(text, " ")
+	return q
+}
+
+// isIssueNumber reports whether w is "#" and digits, like "#12", which is
+// more likely an issue or ticket number than a tag.
+func isIssueNumber(w string) bool {
+	if len(w) < 2 || w[0] != '#' {
+		return false
+	}
+	for _, r := range w[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseDuePhrase reads a due date from the start of words, returning it
+// and how many words it took, or 0 if words do not start with a date.
+func parseDuePhrase(words []string, today time.Time) (time.Time, int) {
+	w := func(i int) string {
+		if i < len(words) {
+			return strings.ToLower(strings.TrimRight(words[i], ",."))
+		}
+		return ""
+	}
+	if d, err := time.ParseInLocation(dateLayout, w(0), time.Local); err == nil {
+		return d, 1
+	}
+	switch w(0) {
+	case "today":
+		return today, 1
+	case "tomorrow", "tmrw":
+		return today.AddDate(0, 0, 1), 1
+	case "on", "due", "by":
+		if d, n := parseDuePhrase(words[1:], today); n > 0 {
+			return d, n + 1
+		}
+	case "next":
+		switch w(1) {
+		case "week":
+			return today.AddDate(0, 0, 7), 2
+		case "month":
+			return today.AddDate(0, 1, 0), 2
+		case "year":
+			return today.AddDate(1, 0, 0), 2
+		}
+		if d, ok := weekdayNames[w(1)]; ok {
+			return nextWeekday(today, d), 2
+		}
+	case "in":
+		n, err := strconv.Atoi(w(1))
+		if err != nil || n < 1 {
+			break
+		}
+		switch strings.TrimSuffix(w(2), "s") {
+		case "day":
+			return today.AddDate(0, 0, n), 3
+		case "week":
+			return today.AddDate(0, 0, 7*n), 3
+		case "month":
+			return today.AddDate(0, n, 0), 3
+		case "year":
+			return today.AddDate(n, 0, 0), 3
+		}
+	}
+	if d, ok := weekdayNames[w(0)]; ok && len(w(0)) > 3 {
+		return nextWeekday(today, d), 1
+	}
+	return time.Time{}, 0
+}
+
+// nextWeekday returns the first day after today that falls on d.
+func nextWeekday(today time.Time, d time.Weekday) time.Time {
+	days := (int(d) - int(today.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return today.AddDate(0, 0, days)
+}
+
+// parseEveryPhrase reads a repeat such as "every 2 weeks" from the start of
+// words, trying the longest phrase first. It returns the RRULE and how many
+// words it took, or 0 if there is none.
+func parseEveryPhrase(words []string) (string, int) {
+	for n := min(len(words), 3); n >= 2; n-- {
+		if r, err := parseRecurrence(strings.Join(words[:n], " ")); err == nil {
+			return r.String(), n
+		}
+	}
+	return "", 0
+}
+
+// preview describes the parsed todo for display under the add prompt.
+func (q quickAdd) preview() string {
+	if q.text == "" {
+		return ""
+	}
+	parts := []string{q.text, q.priority}
+	if q.due != "" {
+		parts = append(parts, "due "+q.due)
+	}
+	if q.recurrence != "" {
+		if r, err := parseRecurrence(q.recurrence); err == nil {
+			parts = append(parts, "repeats "+r.describe())
+		}
+	}
+	for _, t := range q.tags {
+		parts = append(parts, "#"+t)
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseQuickAdd(t *testing.T) {
+	// A Saturday.
+	now := time.Date(2026, 10, 17, 15, 4, 5, 0, time.Local)
+	tests := []struct {
+		in   string
+		want quickAdd
+	}{
+		{"Buy milk", quickAdd{text: "Buy milk", priority: "medium"}},
+		{"", quickAdd{priority: "medium"}},
+		{"Pay rent tomorrow !urgent #home", quickAdd{text: "Pay rent", due: "2026-10-18", priority: "urgent", tags: []string{"home"}}},
+		{"!low +errands #shop Groceries", quickAdd{text: "Groceries", priority: "low", tags: []string{"errands", "shop"}}},
+		{"Call mum !1", quickAdd{text: "Call mum", priority: "urgent"}},
+		{"Report 2026-11-03", quickAdd{text: "Report", due: "2026-11-03", priority: "medium"}},
+		{"Report due 2026-11-03", quickAdd{text: "Report", due: "2026-11-03", priority: "medium"}},
+		{"Ship today", quickAdd{text: "Ship", due: "2026-10-17", priority: "medium"}},
+		{"Gym friday", quickAdd{text: "Gym", due: "2026-10-23", priority: "medium"}},
+		{"Gym next saturday", quickAdd{text: "Gym", due: "2026-10-24", priority: "medium"}},
+		{"Review next week", quickAdd{text: "Review", due: "2026-10-24", priority: "medium"}},
+		{"Renew next month", quickAdd{text: "Renew", due: "2026-11-17", priority: "medium"}},
+		{"Follow up in 3 days", quickAdd{text: "Follow up", due: "2026-10-20", priority: "medium"}},
+		{"Check in 2 weeks", quickAdd{text: "Check", due: "2026-10-31", priority: "medium"}},
+		{"Stand-up every weekday", quickAdd{text: "Stand-up", priority: "medium", recurrence: "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR"}},
+		{"Bins every 2 weeks monday", quickAdd{text: "Bins", due: "2026-10-19", priority: "medium", recurrence: "FREQ=WEEKLY;INTERVAL=2"}},
+		{"Water plants every other day", quickAdd{text: "Water plants", priority: "medium", recurrence: "FREQ=DAILY;INTERVAL=2"}},
+		{"Bins monday every 2 weeks", quickAdd{text: "Bins", due: "2026-10-19", priority: "medium", recurrence: "FREQ=WEEKLY;INTERVAL=2"}},
+		{"Pay rent tomorrow.", quickAdd{text: "Pay rent", due: "2026-10-18", priority: "medium"}},
+		// Dates and repeats only count at the end; earlier ones are text.
+		{"Move today tomorrow", quickAdd{text: "Move today", due: "2026-10-18", priority: "medium"}},
+		{"Call Friday about the lease", quickAdd{text: "Call Friday about the lease", priority: "medium"}},
+		{"Plan every other day of the trip", quickAdd{text: "Plan every other day of the trip", priority: "medium"}},
+		{"Pay rent tomorrow !urgent and more", quickAdd{text: "Pay rent tomorrow and more", priority: "urgent"}},
+		// Short weekday names are too common as words to be dates.
+		{"Sat exam", quickAdd{text: "Sat exam", priority: "medium"}},
+		{"Fix !bug", quickAdd{text: "Fix !bug", priority: "medium"}},
+		{`Close \#bug \today`, quickAdd{text: "Close #bug today", priority: "medium"}},
+		{`Stand-up \every day`, quickAdd{text: "Stand-up every day", priority: "medium"}},
+		// As in Markdown, #12 is an issue number rather than a tag.
+		{"Fix #12 #bug", quickAdd{text: "Fix #12", priority: "medium", tags: []string{"bug"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got := parseQuickAdd(tt.in, now)
+			if got.text != tt.want.text || got.due != tt.want.due || got.priority != tt.want.priority ||
+				!slices.Equal(got.tags, tt.want.tags) || got.recurrence != tt.want.recurrence {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,506 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// recurrence is a repeat schedule, a small subset of an iCalendar RRULE:
+// a frequency and interval, optionally limited to certain weekdays or a
+// day of the month (and a month, for yearly ones), and ending on a date or
+// after a number of times.
+type recurrence struct {
+	freq     string // DAILY, WEEKLY, MONTHLY or YEARLY
+	interval int
+	days     []time.Weekday // BYDAY
+	month    time.Month     // BYMONTH, only for YEARLY
+	monthDay int            // BYMONTHDAY; -1 is the last day of the month
+	until    time.Time      // last date an occurrence may fall on
+	count    int            // occurrences left, including this one; 0 is no limit
+}
+
+// workWeek is Monday to Friday.
+var workWeek = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+var weekdayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+var freqUnits = map[string]string{
+	"day": "DAILY", "days": "DAILY", "daily": "DAILY",
+	"week": "WEEKLY", "weeks": "WEEKLY", "weekly": "WEEKLY",
+	"month": "MONTHLY", "months": "MONTHLY", "monthly": "MONTHLY",
+	"year": "YEARLY", "years": "YEARLY", "yearly": "YEARLY", "annually": "YEARLY",
+}
+
+// parseRecurrence reads a schedule written either as an RRULE
+// ("FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH") or in words: "daily", "every 3
+// days", "every monday", "every mon, thu", "every weekday", "monthly on
+// the 15th", "first of every month", "last day of every month", each
+// optionally followed by "until YYYY-MM-DD" or "N times".
+func parseRecurrence(s string) (recurrence, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "=") {
+		return parseRRule(strings.TrimPrefix(strings.ToUpper(s), "RRULE:"))
+	}
+	return parseFriendlyRecurrence(strings.ToLower(s))
+}
+
+func parseRRule(s string) (recurrence, error) {
+	r := recurrence{interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return r, fmt.Errorf("bad RRULE part %q", part)
+		}
+		switch name {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.freq = value
+			default:
+				return r, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("bad INTERVAL %q", value)
+			}
+			r.interval = n
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				d := weekdayIndex(code)
+				if d < 0 {
+					return r, fmt.Errorf("unsupported BYDAY %q", code)
+				}
+				r.days = append(r.days, time.Weekday(d))
+			}
+		case "BYMONTH":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 12 {
+				return r, fmt.Errorf("bad BYMONTH %q", value)
+			}
+			r.month = time.Month(n)
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(value)
+			if err != nil || n == 0 || n < -1 || n > 31 {
+				return r, fmt.Errorf("bad BYMONTHDAY %q", value)
+			}
+			r.monthDay = n
+		case "UNTIL":
+			t, err := parseUntil(value)
+			if err != nil {
+				return r, fmt.Errorf("bad UNTIL %q", value)
+			}
+			r.until = t
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("bad COUNT %q", value)
+			}
+			r.count = n
+		case "WKST":
+			// Only matters for rules go-do-it does not support anyway.
+		default:
+			return r, fmt.Errorf("unsupported RRULE part %s", name)
+		}
+	}
+	if r.freq == "" {
+		return r, fmt.Errorf("RRULE has no FREQ")
+	}
+	if r.month != 0 && r.freq != "YEARLY" {
+		return r, fmt.Errorf("BYMONTH is only supported with FREQ=YEARLY")
+	}
+	return r, nil
+}
+
+func weekdayIndex(code string) int {
+	for i, c := range weekdayCodes {
+		if c == code {
+			return i
+		}
+	}
+	return -1
+}
+
+// monthIndex returns the month a lowercase name such as "feb" or
+// "february" stands for, or 0.
+func monthIndex(name string) time.Month {
+	for m := time.January; m <= time.December; m++ {
+		full := strings.ToLower(m.String())
+		if name == full || name == full[:3] {
+			return m
+		}
+	}
+	return 0
+}
+
+// parseUntil accepts the date forms an RRULE UNTIL may take.
+func parseUntil(s string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102", dateLayout} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("bad date %q", s)
+}
+
+func parseFriendlyRecurrence(s string) (recurrence, error) {
+	r := recurrence{interval: 1}
+	bad := fmt.Errorf("don't know how to repeat %q (try \"every 2 weeks\" or FREQ=WEEKLY;BYDAY=MO)", s)
+
+	// Endings come last: "until 2025-12-31", "10 times", "for 10 times".
+	if rest, date, ok := strings.Cut(s, " until "); ok {
+		t, err := time.ParseInLocation(dateLayout, strings.TrimSpace(date), time.Local)
+		if err != nil {
+			return r, fmt.Errorf("bad until date %q (want YYYY-MM-DD)", strings.TrimSpace(date))
+		}
+		r.until = t
+		s = rest
+	}
+	if rest, ok := strings.CutSuffix(strings.TrimSuffix(s, "s"), " time"); ok {
+		i := strings.LastIndex(rest, " ")
+		n, err := strconv.Atoi(rest[i+1:])
+		if err != nil || n < 1 {
+			return r, bad
+		}
+		r.count = n
+		s = strings.TrimSuffix(strings.TrimSpace(rest[:max(i, 0)]), " for")
+	}
+	s = strings.Join(strings.Fields(strings.ReplaceAll(s, ",", " ")), " ")
+
+	switch s {
+	case "first of every month", "first of the month", "first day of every month", "monthly on the 1st":
+		r.freq, r.monthDay = "MONTHLY", 1
+		return r, nil
+	case "last day of every month", "last of every month", "last day of the month", "end of every month":
+		r.freq, r.monthDay = "MONTHLY", -1
+		return r, nil
+	case "every weekday", "weekdays":
+		r.freq = "DAILY"
+		r.days = workWeek
+		return r, nil
+	case "every weekend", "weekends":
+		r.freq = "WEEKLY"
+		r.days = []time.Weekday{time.Saturday, time.Sunday}
+		return r, nil
+	}
+
+	// "every 3 days", "weekly on mon", "every month on the 15th".
+	words := strings.Fields(s)
+	if len(words) > 0 && words[0] == "every" {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		return r, bad
+	}
+
+	// "every other week" and "every 3 days".
+	if words[0] == "other" {
+		r.interval = 2
+		words = words[1:]
+	} else if n, err := strconv.Atoi(words[0]); err == nil {
+		if n < 1 {
+			return r, bad
+		}
+		r.interval = n
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		return r, bad
+	}
+
+	if f, ok := freqUnits[words[0]]; ok {
+		r.freq = f
+		words = words[1:]
+		if len(words) > 0 && words[0] == "on" {
+			words = words[1:]
+		}
+	} else if _, ok := weekdayNames[words[0]]; ok {
+		r.freq = "WEEKLY"
+	} else {
+		return r, bad
+	}
+
+	for _, w := range words {
+		if w == "the" || w == "and" || w == "on" || w == "in" {
+			continue
+		}
+		if d, ok := weekdayNames[strings.TrimSuffix(w, "s")]; ok {
+			r.days = append(r.days, d)
+			continue
+		}
+		if d, ok := weekdayNames[w]; ok {
+			r.days = append(r.days, d)
+			continue
+		}
+		if r.freq == "YEARLY" && r.month == 0 {
+			if m := monthIndex(w); m != 0 {
+				r.month = m
+				continue
+			}
+		}
+		if (r.freq == "MONTHLY" || r.freq == "YEARLY") && r.monthDay == 0 {
+			if n, err := strconv.Atoi(strings.TrimRight(w, "stndrh")); err == nil && n >= 1 && n <= 31 {
+				r.monthDay = n
+				continue
+			}
+			if w == "last" {
+				r.monthDay = -1
+				continue
+			}
+		}
+		if w == "day" && r.monthDay == -1 {
+			continue
+		}
+		return r, bad
+	}
+	if len(r.days) > 0 && (r.freq == "MONTHLY" || r.freq == "YEARLY") {
+		return r, fmt.Errorf("weekdays can only be given for daily or weekly repeats")
+	}
+	return r, nil
+}
+
+// String returns the schedule as an RRULE, the form stored on todos.
+func (r recurrence) String() string {
+	parts := []string{"FREQ=" + r.freq}
+	if r.interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.interval))
+	}
+	if len(r.days) > 0 {
+		codes := make([]string, len(r.days))
+		for i, d := range r.days {
+			codes[i] = weekdayCodes[d]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.month != 0 {
+		parts = append(parts, fmt.Sprintf("BYMONTH=%d", r.month))
+	}
+	if r.monthDay != 0 {
+		parts = append(parts, fmt.Sprintf("BYMONTHDAY=%d", r.monthDay))
+	}
+	if !r.until.IsZero() {
+		parts = append(parts, "UNTIL="+r.until.Format("20060102"))
+	}
+	if r.count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// describe returns the schedule in words, e.g. "every 2 weeks on Mon, Thu",
+// in a form parseRecurrence reads back.
+func (r recurrence) describe() string {
+	unit := map[string]string{"DAILY": "day", "WEEKLY": "week", "MONTHLY": "month", "YEARLY": "year"}[r.freq]
+	s := "every " + unit
+	if r.interval > 1 {
+		s = fmt.Sprintf("every %d %ss", r.interval, unit)
+	}
+	if len(r.days) > 0 {
+		names := make([]string, len(r.days))
+		for i, d := range r.days {
+			names[i] = d.String()[:3]
+		}
+		s += " on " + strings.Join(names, ", ")
+	}
+	if r.month != 0 {
+		s += " in " + r.month.String()[:3]
+	}
+	switch {
+	case r.monthDay == -1:
+		s += " on the last day"
+	case r.monthDay > 0:
+		s += " on the " + ordinal(r.monthDay)
+	}
+	if r.count > 0 {
+		s += fmt.Sprintf(" %d time", r.count)
+		if r.count > 1 {
+			s += "s"
+		}
+	}
+	if !r.until.IsZero() {
+		s += " until " + r.until.Format(dateLayout)
+	}
+	return s
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// recurrenceInput turns what was typed at the repeat prompt into the RRULE
+// stored on a todo; blank means the todo does not repeat.
+func recurrenceInput(s string) (string, error) {
+	if strings.TrimSpace(s) == "" {
+		return "", nil
+	}
+	r, err := parseRecurrence(s)
+	if err != nil {
+		return "", err
+	}
+	return r.String(), nil
+}
+
+// after returns the first occurrence strictly after from, and false if the
+// schedule has run out.
+func (r recurrence) after(from time.Time) (time.Time, bool) {
+	if r.count == 1 {
+		return time.Time{}, false
+	}
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	var next time.Time
+	switch r.freq {
+	case "DAILY":
+		next = from.AddDate(0, 0, r.interval)
+		// Within seven steps every weekday the interval can reach has come
+		// up; an interval of whole weeks only ever reaches from's weekday.
+		for n := 0; len(r.days) > 0 && !r.onDay(next); n++ {
+			if n == 7 {
+				return time.Time{}, false
+			}
+			next = next.AddDate(0, 0, r.interval)
+		}
+	case "WEEKLY":
+		if len(r.days) == 0 {
+			next = from.AddDate(0, 0, 7*r.interval)
+			break
+		}
+		// Days later in the same week come first, then the matching days
+		// of the week interval weeks on.
+		start := weekStart(from)
+		next = from.AddDate(0, 0, 1)
+		for !r.onDay(next) || weeksBetween(start, weekStart(next))%r.interval != 0 {
+			next = next.AddDate(0, 0, 1)
+		}
+	case "MONTHLY":
+		day := r.monthDay
+		if day == 0 {
+			day = from.Day()
+		}
+		next = monthDay(from.Year(), from.Month()+time.Month(r.interval), day)
+	case "YEARLY":
+		month, day := r.month, r.monthDay
+		if month == 0 {
+			month = from.Month()
+		}
+		if day == 0 {
+			day = from.Day()
+		}
+		next = monthDay(from.Year(), month, day)
+		if !next.After(from) {
+			next = monthDay(from.Year()+r.interval, month, day)
+		}
+	}
+	if !r.until.IsZero() && next.After(r.until) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// anchored pins a monthly or yearly schedule to from's day where a short
+// month would otherwise move it for good: a todo due on the 31st comes back
+// on the 30th in a 30-day month and on the 31st again after it, and one due
+// on 29 February on the 28th until the next leap year.
+func (r recurrence) anchored(from time.Time) recurrence {
+	switch {
+	case r.freq == "MONTHLY" && r.monthDay == 0 && from.Day() > 28:
+		r.monthDay = from.Day()
+	case r.freq == "YEARLY" && r.monthDay == 0 && from.Month() == time.February && from.Day() == 29:
+		r.month, r.monthDay = time.February, 29
+	}
+	return r
+}
+
+func (r recurrence) onDay(t time.Time) bool {
+	for _, d := range r.days {
+		if t.Weekday() == d {
+			return true
+		}
+	}
+	return false
+}
+
+// weekStart returns the Monday of t's week.
+func weekStart(t time.Time) time.Time {
+	return t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+}
+
+func weeksBetween(a, b time.Time) int {
+	return int(math.Round(b.Sub(a).Hours() / (24 * 7)))
+}
+
+// monthDay returns the given day of a month, clamped to the month's last
+// day; day -1 is the last day.
+func monthDay(year int, month time.Month, day int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.Local)
+	last := first.AddDate(0, 1, -1).Day()
+	if day == -1 || day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.Local)
+}
+
+// nextOccurrence returns the todo that follows t once t is done: a copy
+// with a fresh ID and the next due date, or false if t does not repeat or
+// its schedule has ended. Occurrences missed while the todo sat overdue
+// are skipped, so the new one is never already overdue.
+func nextOccurrence(t Todo, now time.Time) (Todo, bool) {
+	if t.Recurrence == "" {
+		return Todo{}, false
+	}
+	r, err := parseRecurrence(t.Recurrence)
+	if err != nil {
+		return Todo{}, false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	from := today
+	if due, err := time.ParseInLocation(dateLayout, t.DueDate, time.Local); err == nil {
+		from = due
+	}
+	r = r.anchored(from)
+	next, ok := r.after(from)
+	for ok && next.Before(today) {
+		next, ok = r.after(next)
+	}
+	if !ok {
+		return Todo{}, false
+	}
+	if r.count > 0 {
+		r.count--
+	}
+
+	n := newTodo(t.Text)
+	n.Priority = t.Priority
+	n.Tags = append([]string(nil), t.Tags...)
+	n.ParentID = t.ParentID
+	n.DueDate = next.Format(dateLayout)
+	n.Recurrence = r.String()
+	return n, true
+}
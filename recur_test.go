@@ -0,0 +1,214 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func date(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.ParseInLocation(dateLayout, s, time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // RRULE, or "" for an error
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"rrule:freq=weekly;interval=2;byday=mo,th", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29"},
+		{"FREQ=DAILY;UNTIL=20261231T000000Z", "FREQ=DAILY;UNTIL=20261231"},
+		{"FREQ=WEEKLY;COUNT=3;WKST=MO", "FREQ=WEEKLY;COUNT=3"},
+		{"FREQ=HOURLY", ""},
+		{"FREQ=DAILY;INTERVAL=0", ""},
+		{"FREQ=WEEKLY;BYDAY=1MO", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", ""},
+		{"FREQ=MONTHLY;BYMONTH=2", ""},
+		{"FREQ=YEARLY;BYMONTH=13", ""},
+		{"FREQ=DAILY;BYSETPOS=1", ""},
+		{"INTERVAL=2", ""},
+		{"daily", "FREQ=DAILY"},
+		{"every 3 days", "FREQ=DAILY;INTERVAL=3"},
+		{"every other week", "FREQ=WEEKLY;INTERVAL=2"},
+		{"every mon, thu", "FREQ=WEEKLY;BYDAY=MO,TH"},
+		{"every weekday", "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR"},
+		{"monthly on the 15th", "FREQ=MONTHLY;BYMONTHDAY=15"},
+		{"last day of every month", "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{"every year in feb on the 29th", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29"},
+		{"every week until 2026-12-31", "FREQ=WEEKLY;UNTIL=20261231"},
+		{"yearly 5 times", "FREQ=YEARLY;COUNT=5"},
+		{"every month on mon", ""},
+		{"whenever", ""},
+		{"every 0 days", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			r, err := parseRecurrence(tt.in)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("got %s, want an error", r)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.String(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			// describe is what the edit prompt shows; it has to read back.
+			back, err := parseRecurrence(r.describe())
+			if err != nil {
+				t.Fatalf("describe %q: %v", r.describe(), err)
+			}
+			if back.String() != tt.want {
+				t.Errorf("describe %q reads back as %s", r.describe(), back)
+			}
+		})
+	}
+}
+
+func TestRecurrenceAfter(t *testing.T) {
+	tests := []struct {
+		rule string
+		from string
+		want []string // successive occurrences; "" where the schedule ends
+	}{
+		{"FREQ=DAILY", "2026-10-17", []string{"2026-10-18", "2026-10-19"}},
+		{"FREQ=DAILY;INTERVAL=3", "2026-10-17", []string{"2026-10-20", "2026-10-23"}},
+		// 2026-10-16 is a Friday.
+		{"FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", "2026-10-16", []string{"2026-10-19", "2026-10-20"}},
+		{"FREQ=DAILY;INTERVAL=2;BYDAY=MO", "2026-10-13", []string{"2026-10-19", "2026-11-02"}},
+		{"FREQ=DAILY;INTERVAL=7;BYDAY=TU", "2026-10-13", []string{"2026-10-20", "2026-10-27"}},
+		// An interval of whole weeks never reaches Monday from a Tuesday.
+		{"FREQ=DAILY;INTERVAL=7;BYDAY=MO", "2026-10-13", []string{""}},
+		{"FREQ=DAILY;INTERVAL=14;BYDAY=SA,SU", "2026-10-13", []string{""}},
+		{"FREQ=WEEKLY", "2026-10-17", []string{"2026-10-24"}},
+		{"FREQ=WEEKLY;BYDAY=MO,TH", "2026-10-13", []string{"2026-10-15", "2026-10-19", "2026-10-22"}},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", "2026-10-15", []string{"2026-10-26", "2026-10-29", "2026-11-09"}},
+		{"FREQ=MONTHLY", "2026-01-15", []string{"2026-02-15", "2026-03-15"}},
+		{"FREQ=MONTHLY;BYMONTHDAY=31", "2026-01-31", []string{"2026-02-28", "2026-03-31", "2026-04-30", "2026-05-31"}},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "2026-01-31", []string{"2026-02-28", "2026-03-31", "2026-04-30"}},
+		{"FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1", "2026-01-01", []string{"2026-04-01", "2026-07-01"}},
+		{"FREQ=YEARLY", "2026-10-17", []string{"2027-10-17"}},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", "2024-02-29", []string{"2025-02-28", "2026-02-28", "2027-02-28", "2028-02-29"}},
+		{"FREQ=YEARLY;BYMONTH=3", "2026-01-10", []string{"2026-03-10", "2027-03-10"}},
+		{"FREQ=DAILY;UNTIL=20261019", "2026-10-17", []string{"2026-10-18", "2026-10-19", ""}},
+		{"FREQ=DAILY;COUNT=1", "2026-10-17", []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			r, err := parseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			from := date(t, tt.from)
+			for _, want := range tt.want {
+				next, ok := r.after(from)
+				got := ""
+				if ok {
+					got = next.Format(dateLayout)
+				}
+				if got != want {
+					t.Fatalf("after %s = %q, want %q", from.Format(dateLayout), got, want)
+				}
+				from = next
+			}
+		})
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	tests := []struct {
+		name     string
+		todo     Todo
+		now      string
+		want     []string // due dates of successive occurrences; "" where they end
+		wantRule string   // the rule on the last occurrence
+	}{
+		{
+			name: "does not repeat",
+			todo: Todo{DueDate: "2026-10-17"},
+			now:  "2026-10-17",
+			want: []string{""},
+		},
+		{
+			name:     "skips missed occurrences",
+			todo:     Todo{DueDate: "2026-10-01", Recurrence: "FREQ=WEEKLY"},
+			now:      "2026-10-17",
+			want:     []string{"2026-10-22"},
+			wantRule: "FREQ=WEEKLY",
+		},
+		{
+			name:     "no due date repeats from today",
+			todo:     Todo{Recurrence: "FREQ=DAILY"},
+			now:      "2026-10-17",
+			want:     []string{"2026-10-18"},
+			wantRule: "FREQ=DAILY",
+		},
+		{
+			name:     "count runs out",
+			todo:     Todo{DueDate: "2026-10-17", Recurrence: "FREQ=DAILY;COUNT=3"},
+			now:      "2026-10-17",
+			want:     []string{"2026-10-18", "2026-10-19", ""},
+			wantRule: "FREQ=DAILY;COUNT=1",
+		},
+		{
+			name:     "monthly on the 31st keeps its day",
+			todo:     Todo{DueDate: "2026-01-31", Recurrence: "FREQ=MONTHLY"},
+			now:      "2026-01-31",
+			want:     []string{"2026-02-28", "2026-03-31", "2026-04-30", "2026-05-31"},
+			wantRule: "FREQ=MONTHLY;BYMONTHDAY=31",
+		},
+		{
+			name:     "monthly on an early day is left alone",
+			todo:     Todo{DueDate: "2026-01-15", Recurrence: "FREQ=MONTHLY"},
+			now:      "2026-01-15",
+			want:     []string{"2026-02-15", "2026-03-15"},
+			wantRule: "FREQ=MONTHLY",
+		},
+		{
+			name:     "yearly on 29 February keeps its day",
+			todo:     Todo{DueDate: "2024-02-29", Recurrence: "FREQ=YEARLY"},
+			now:      "2024-02-29",
+			want:     []string{"2025-02-28", "2026-02-28", "2027-02-28", "2028-02-29"},
+			wantRule: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cur := tt.todo
+			cur.Text = "x"
+			for _, want := range tt.want {
+				now := date(t, tt.now)
+				if cur.DueDate != "" && cur.DueDate > tt.now {
+					now = date(t, cur.DueDate)
+				}
+				next, ok := nextOccurrence(cur, now)
+				got := ""
+				if ok {
+					got = next.DueDate
+				}
+				if got != want {
+					t.Fatalf("after %s: got %q, want %q", cur.DueDate, got, want)
+				}
+				if !ok {
+					break
+				}
+				if next.ID == cur.ID || next.Text != cur.Text {
+					t.Errorf("next occurrence %+v does not follow %+v", next, cur)
+				}
+				cur = next
+			}
+			if tt.wantRule != "" && cur.Recurrence != tt.wantRule {
+				t.Errorf("rule = %s, want %s", cur.Recurrence, tt.wantRule)
+			}
+		})
+	}
+}
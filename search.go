@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// fuzzyMatch reports whether pattern matches s, ignoring case, and returns
+// the rune positions in s that matched. A contiguous substring match is
+// preferred; otherwise the runes of pattern must appear in s in order.
+func fuzzyMatch(pattern, s string) ([]int, bool) {
+	p := lowerRunes(pattern)
+	r := lowerRunes(s)
+	if len(p) == 0 {
+		return nil, true
+	}
+	if i := runeIndex(r, p); i >= 0 {
+		pos := make([]int, len(p))
+		for j := range p {
+			pos[j] = i + j
+		}
+		return pos, true
+	}
+	var pos []int
+	for i := 0; i < len(r) && len(pos) < len(p); i++ {
+		if r[i] == p[len(pos)] {
+			pos = append(pos, i)
+		}
+	}
+	return pos, len(pos) == len(p)
+}
+
+// lowerRunes lowercases s rune by rune, so that positions in the result
+// are positions in s.
+func lowerRunes(s string) []rune {
+	r := []rune(s)
+	for i := range r {
+		r[i] = unicode.ToLower(r[i])
+	}
+	return r
+}
+
+func runeIndex(s, sub []rune) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		match := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// searchHit records where a search matched a todo.
+type searchHit struct {
+	text  []int   // matched rune positions in Text
+	tags  [][]int // matched rune positions per tag, nil where it didn't match
+	notes bool    // the notes contain the pattern
+}
+
+// searchTodo matches pattern against a todo's text and tags, and its
+// notes. Notes are long enough that almost any pattern would fuzzy-match
+// them, so they have to contain it as it is typed, ignoring case.
+func searchTodo(t Todo, pattern string) (searchHit, bool) {
+	var hit searchHit
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return hit, false
+	}
+	found := false
+	if pos, ok := fuzzyMatch(pattern, t.Text); ok {
+		hit.text = pos
+		found = true
+	}
+	for i, tag := range t.Tags {
+		if pos, ok := fuzzyMatch(strings.TrimPrefix(pattern, "#"), tag); ok {
+			if hit.tags == nil {
+				hit.tags = make([][]int, len(t.Tags))
+			}
+			hit.tags[i] = pos
+			found = true
+		}
+	}
+	if strings.Contains(strings.ToLower(t.Notes), strings.ToLower(pattern)) {
+		hit.notes = true
+		found = true
+	}
+	return hit, found
+}
+
+// highlight renders s cut to width cells in style base, with the runes at
+// positions pos in style hl instead. When s is cut short it ends in an
+// ellipsis, which is never highlighted.
+func highlight(s string, width int, pos []int, base, hl lipgloss.Style) string {
+	if width <= 0 {
+		return ""
+	}
+	ellipsis := ""
+	if ansi.StringWidth(s) > width {
+		s = ansi.Truncate(s, width-1, "")
+		ellipsis = "…"
+	}
+	marked := make(map[int]bool, len(pos))
+	for _, p := range pos {
+		marked[p] = true
+	}
+	var b, run strings.Builder
+	runMarked := false
+	flush := func() {
+		if run.Len() == 0 {
+			return
+		}
+		if runMarked {
+			b.WriteString(hl.Render(run.String()))
+		} else {
+			b.WriteString(base.Render(run.String()))
+		}
+		run.Reset()
+	}
+	for i, r := range []rune(s) {
+		if mk := marked[i] && !unicode.IsSpace(r); mk != runMarked {
+			flush()
+			runMarked = mk
+		}
+		run.WriteRune(r)
+	}
+	flush()
+	if ellipsis != "" {
+		b.WriteString(base.Render(ellipsis))
+	}
+	return b.String()
+}
+
+// padRight pads a possibly styled string with spaces to width cells.
+func padRight(s string, width int) string {
+	if w := lipgloss.Width(s); w < width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	return s
+}
+
+// searchPattern is the search in effect: what is being typed in search
+// mode, or the last search confirmed with Enter.
+func (m *model) searchPattern() string {
+	if m.mode == modeSearch {
+		return strings.TrimSpace(m.searchInput.Value())
+	}
+	return m.search
+}
+
+// jumpMatch moves the cursor to the next (dir 1) or previous (dir -1) row
+// matching the search, wrapping around like vim.
+func (m *model) jumpMatch(dir int) {
+	if m.search == "" {
+		m.status = "No search. Press / to search."
+		return
+	}
+	n := len(m.rows)
+	for step := 1; step <= n; step++ {
+		r := ((m.cursor+dir*step)%n + n) % n
+		if _, ok := searchTodo(m.todos[m.rows[r]], m.search); ok {
+			m.status = fmt.Sprintf("/%s", m.search)
+			if (dir > 0 && r <= m.cursor) || (dir < 0 && r >= m.cursor) {
+				if dir > 0 {
+					m.status = "Search hit BOTTOM, continuing at TOP"
+				} else {
+					m.status = "Search hit TOP, continuing at BOTTOM"
+				}
+			}
+			m.cursor = r
+			return
+		}
+	}
+	m.status = fmt.Sprintf("Pattern not found: %s", m.search)
+}
+
+// countMatches returns how many shown todos match the search.
+func (m *model) countMatches() int {
+	n := 0
+	for _, i := range m.rows {
+		if _, ok := searchTodo(m.todos[i], m.searchPattern()); ok {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       []int
+		ok         bool
+	}{
+		{"", "Pay rent", nil, true},
+		{"rent", "Pay rent", []int{4, 5, 6, 7}, true},
+		{"RENT", "pay Rent", []int{4, 5, 6, 7}, true},
+		{"prt", "Pay rent", []int{0, 4, 7}, true},
+		{"éa", "Café au lait", []int{3, 5}, true},
+		{"tp", "Pay rent", nil, false},
+		{"rents", "Pay rent", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := fuzzyMatch(tt.pattern, tt.s)
+		if ok != tt.ok || (ok && !slices.Equal(got, tt.want)) {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, %v; want %v, %v", tt.pattern, tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSearchTodo(t *testing.T) {
+	todo := Todo{Text: "Pay rent", Tags: []string{"home", "money"}, Notes: "Bank details are in the drawer"}
+	tests := []struct {
+		pattern string
+		ok      bool
+		text    bool
+		tags    []bool
+		notes   bool
+	}{
+		{"", false, false, nil, false},
+		{"rent", true, true, nil, false},
+		{"#home", true, false, []bool{true, false}, false},
+		{"mo", true, false, []bool{false, true}, false},
+		{"drawer", true, false, nil, true},
+		{"bdrw", false, false, nil, false}, // notes are not fuzzy-matched
+	}
+	for _, tt := range tests {
+		hit, ok := searchTodo(todo, tt.pattern)
+		var tags []bool
+		for _, pos := range hit.tags {
+			tags = append(tags, pos != nil)
+		}
+		if ok != tt.ok || (hit.text != nil) != tt.text || !slices.Equal(tags, tt.tags) || hit.notes != tt.notes {
+			t.Errorf("searchTodo(%q) = %+v, %v", tt.pattern, hit, ok)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	upper := lipgloss.NewStyle().Transform(strings.ToUpper)
+	tests := []struct {
+		s     string
+		width int
+		pos   []int
+		want  string
+	}{
+		{"pay rent", 20, []int{0, 4, 7}, "Pay RenT"},
+		{"pay rent", 20, []int{3, 4}, "pay Rent"}, // spaces are not marked
+		{"pay the rent", 8, []int{8, 9}, "pay the…"},
+		{"pay the rent", 8, []int{6, 7}, "pay thE…"},
+		{"café au lait", 20, []int{3}, "cafÉ au lait"},
+		{"pay rent", 0, []int{0}, ""},
+	}
+	for _, tt := range tests {
+		if got := highlight(tt.s, tt.width, tt.pos, lipgloss.NewStyle(), upper); got != tt.want {
+			t.Errorf("highlight(%q, %d, %v) = %q, want %q", tt.s, tt.width, tt.pos, got, tt.want)
+		}
+	}
+}
+
+func TestModelSearch(t *testing.T) {
+	m := initialModel(newMemoryStore(newTodo("Pay rent"), newTodo("Write report"), newTodo("Rent a car")))
+
+	m = press(m, "/", "rent")
+	if len(m.rows) != 2 {
+		t.Errorf("typing a search shows %d rows, want 2", len(m.rows))
+	}
+	m = press(m, "enter")
+	if want := `2 match(es) for "rent". n/N to jump, esc to clear.`; m.status != want {
+		t.Errorf("status %q, want %q", m.status, want)
+	}
+	if len(m.rows) != 3 {
+		t.Errorf("a confirmed search shows %d rows, want all 3", len(m.rows))
+	}
+	m = press(m, "n")
+	if i, _ := m.selected(); m.todos[i].Text != "Rent a car" {
+		t.Errorf("n moved to %q", m.todos[i].Text)
+	}
+	m = press(m, "n")
+	if i, _ := m.selected(); m.todos[i].Text != "Pay rent" || !strings.Contains(m.status, "BOTTOM") {
+		t.Errorf("n moved to %q, status %q", m.todos[i].Text, m.status)
+	}
+	m = press(m, "esc", "n")
+	if !strings.HasPrefix(m.status, "No search") {
+		t.Errorf("n after clearing the search: status %q", m.status)
+	}
+}
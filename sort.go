@@ -0,0 +1,102 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// sortKey orders the rows of the todo table.
+type sortKey int
+
+const (
+	sortNone sortKey = iota // list order
+	sortPriority
+	sortDue
+	sortCreated
+	sortAlpha
+	sortTag
+	sortCompletion
+)
+
+var sortKeyNames = []string{"none", "priority", "due", "created", "alpha", "tag", "done"}
+
+func (k sortKey) String() string {
+	return sortKeyNames[k]
+}
+
+func parseSortKey(s string) sortKey {
+	for i, name := range sortKeyNames {
+		if name == s {
+			return sortKey(i)
+		}
+	}
+	return sortNone
+}
+
+// next cycles to the following sort key.
+func (k sortKey) next() sortKey {
+	return (k + 1) % sortKey(len(sortKeyNames))
+}
+
+func priorityRank(p string) int {
+	switch p {
+	case "urgent":
+		return 0
+	case "low":
+		return 2
+	}
+	return 1
+}
+
+// compareTodos orders a and b by key, returning <0, 0 or >0. Todos without
+// a due date or tag sort last whichever way the list is sorted.
+func compareTodos(a, b Todo, key sortKey, desc bool) int {
+	c := 0
+	switch key {
+	case sortPriority:
+		c = priorityRank(a.Priority) - priorityRank(b.Priority)
+	case sortDue:
+		if a.DueDate == "" || b.DueDate == "" {
+			return boolRank(a.DueDate == "") - boolRank(b.DueDate == "")
+		}
+		c = strings.Compare(a.DueDate, b.DueDate)
+	case sortCreated:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	case sortAlpha:
+		c = strings.Compare(strings.ToLower(a.Text), strings.ToLower(b.Text))
+	case sortTag:
+		if len(a.Tags) == 0 || len(b.Tags) == 0 {
+			return boolRank(len(a.Tags) == 0) - boolRank(len(b.Tags) == 0)
+		}
+		c = strings.Compare(strings.ToLower(a.Tags[0]), strings.ToLower(b.Tags[0]))
+	case sortCompletion:
+		c = boolRank(a.Done) - boolRank(b.Done)
+	}
+	if desc {
+		return -c
+	}
+	return c
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// sortRows orders rows (indexes into todos) by key, keeping list order
+// between equal todos.
+func sortRows(rows []int, todos []Todo, key sortKey, desc bool) {
+	if key == sortNone {
+		if desc {
+			for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+				rows[i], rows[j] = rows[j], rows[i]
+			}
+		}
+		return
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return compareTodos(todos[rows[i]], todos[rows[j]], key, desc) < 0
+	})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSortRows(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, time.Local) }
+	todos := []Todo{
+		{Text: "b", Priority: "low", DueDate: "2026-10-20", Tags: []string{"work"}, CreatedAt: day(3)},
+		{Text: "C", Priority: "urgent", CreatedAt: day(1), Done: true},
+		{Text: "a", Priority: "medium", DueDate: "2026-10-18", Tags: []string{"Home"}, CreatedAt: day(2)},
+		{Text: "d", Priority: "urgent", DueDate: "2026-10-18", CreatedAt: day(4)},
+	}
+	tests := []struct {
+		key  sortKey
+		desc bool
+		want string
+	}{
+		{sortNone, false, "bCad"},
+		{sortNone, true, "daCb"},
+		{sortPriority, false, "Cdab"},
+		{sortPriority, true, "baCd"},
+		{sortDue, false, "adbC"},
+		{sortDue, true, "badC"}, // no due date stays last
+		{sortCreated, false, "Cabd"},
+		{sortAlpha, false, "abCd"},
+		{sortTag, false, "abCd"},
+		{sortTag, true, "baCd"},
+		{sortCompletion, false, "badC"},
+		{sortCompletion, true, "Cbad"},
+	}
+	for _, tt := range tests {
+		rows := []int{0, 1, 2, 3}
+		sortRows(rows, todos, tt.key, tt.desc)
+		got := ""
+		for _, i := range rows {
+			got += todos[i].Text
+		}
+		if got != tt.want {
+			t.Errorf("sort by %s (desc %v) = %s, want %s", tt.key, tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestSortKeyNames(t *testing.T) {
+	var seen []sortKey
+	for k := sortNone; ; k = k.next() {
+		if slices.Contains(seen, k) {
+			break
+		}
+		seen = append(seen, k)
+		if parseSortKey(k.String()) != k {
+			t.Errorf("parseSortKey(%q) = %v", k.String(), parseSortKey(k.String()))
+		}
+	}
+	if len(seen) != len(sortKeyNames) {
+		t.Errorf("next() visits %d keys, want %d", len(seen), len(sortKeyNames))
+	}
+	if parseSortKey("nope") != sortNone {
+		t.Error("an unknown sort key is not sortNone")
+	}
+}
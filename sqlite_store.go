@@ -0,0 +1,352 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteFile is where the SQLite database used to live before the data
+// directory; see dataFile.
+const sqliteFile = "todolist.db"
+
+// sqliteMigrations bring a database up to date one step at a time;
+// PRAGMA user_version records how many have been applied.
+var sqliteMigrations = []func(tx *sql.Tx) error{
+	execSQL(sqliteSchemaV1),
+	migrateSQLiteIDs,
+	execSQL(`ALTER TABLE todos ADD COLUMN parent_uid TEXT NOT NULL DEFAULT '';
+		CREATE INDEX todos_parent_uid ON todos(parent_uid);`),
+	execSQL(`ALTER TABLE todos ADD COLUMN recurrence TEXT NOT NULL DEFAULT ''`),
+	execSQL(`ALTER TABLE todos ADD COLUMN notes TEXT NOT NULL DEFAULT ''`),
+}
+
+const sqliteSchemaV1 = `
+CREATE TABLE todos (
+	id       INTEGER PRIMARY KEY,
+	position INTEGER NOT NULL,
+	text     TEXT    NOT NULL,
+	priority TEXT    NOT NULL DEFAULT 'medium',
+	due_date TEXT    NOT NULL DEFAULT '',
+	done     INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX todos_position ON todos(position);
+CREATE INDEX todos_due_date ON todos(due_date);
+CREATE INDEX todos_done_priority ON todos(done, priority);
+
+CREATE TABLE tags (
+	id   INTEGER PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE todo_tags (
+	todo_id  INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+	tag_id   INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	PRIMARY KEY (todo_id, tag_id)
+);
+CREATE INDEX todo_tags_tag ON todo_tags(tag_id);
+`
+
+// migrateSQLiteIDs adds stable IDs and timestamps, backfilling existing rows.
+func migrateSQLiteIDs(tx *sql.Tx) error {
+	for _, stmt := range []string{
+		"ALTER TABLE todos ADD COLUMN uid TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE todos ADD COLUMN created_at TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE todos ADD COLUMN updated_at TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE todos ADD COLUMN completed_at TEXT NOT NULL DEFAULT ''",
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	rows, err := tx.Query("SELECT id, done FROM todos")
+	if err != nil {
+		return err
+	}
+	type row struct {
+		id   int64
+		done bool
+	}
+	var existing []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.done); err != nil {
+			rows.Close()
+			return err
+		}
+		existing = append(existing, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	now := formatTime(time.Now())
+	for _, r := range existing {
+		completed := ""
+		if r.done {
+			completed = now
+		}
+		if _, err := tx.Exec("UPDATE todos SET uid = ?, created_at = ?, updated_at = ?, completed_at = ? WHERE id = ?",
+			newID(), now, now, completed, r.id); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec("CREATE UNIQUE INDEX todos_uid ON todos(uid)")
+	return err
+}
+
+func execSQL(query string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
+// sqliteStore keeps todos in an embedded SQLite database, with tags
+// normalized into their own table. Single-item changes only touch the
+// affected rows.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// openSQLiteStore opens (or creates) the database at path. A newly created
+// database is seeded once from the JSON-lines file at legacyPath, if any.
+func openSQLiteStore(path, legacyPath string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	s := &sqliteStore{db: db}
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if version > len(sqliteMigrations) {
+		db.Close()
+		return nil, fmt.Errorf("%s was written by a newer go-do-it (schema %d)", path, version)
+	}
+	if version < len(sqliteMigrations) {
+		// The whole upgrade is one transaction, and a new database is
+		// seeded in the same one, so a failed import leaves no half-made
+		// database behind and is tried again next time.
+		err := s.inTx(func(tx *sql.Tx) error {
+			for v := version; v < len(sqliteMigrations); v++ {
+				if err := sqliteMigrations[v](tx); err != nil {
+					return fmt.Errorf("migrate %s to schema %d: %w", path, v+1, err)
+				}
+			}
+			if version == 0 {
+				if err := migrateFrom(tx, legacyPath); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", len(sqliteMigrations)))
+			return err
+		})
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// migrateFrom imports the todos of a JSON-lines file into the database.
+func migrateFrom(tx *sql.Tx, path string) error {
+	if path == "" {
+		return nil
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	todos, _, skipped, err := loadTodos(path)
+	if err != nil {
+		return fmt.Errorf("migrate %s: %w", path, err)
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "%v; they were not imported\n", &skippedLinesError{path: path, lines: skipped})
+	}
+	if err := replaceTodos(tx, todos); err != nil {
+		return fmt.Errorf("migrate %s: %w", path, err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) Load() ([]Todo, error) {
+	rows, err := s.db.Query(`SELECT id, uid, parent_uid, recurrence, notes, text, priority, due_date, done, created_at, updated_at, completed_at
+		FROM todos ORDER BY position`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	todos := []Todo{}
+	byID := make(map[int64]int)
+	for rows.Next() {
+		var id int64
+		var t Todo
+		var created, updated, completed string
+		if err := rows.Scan(&id, &t.ID, &t.ParentID, &t.Recurrence, &t.Notes, &t.Text, &t.Priority, &t.DueDate, &t.Done, &created, &updated, &completed); err != nil {
+			return nil, err
+		}
+		t.CreatedAt = parseTime(created)
+		t.UpdatedAt = parseTime(updated)
+		t.CompletedAt = parseTime(completed)
+		byID[id] = len(todos)
+		todos = append(todos, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tagRows, err := s.db.Query(`SELECT tt.todo_id, t.name FROM todo_tags tt
+		JOIN tags t ON t.id = tt.tag_id ORDER BY tt.todo_id, tt.position`)
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var id int64
+		var name string
+		if err := tagRows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		if i, ok := byID[id]; ok {
+			todos[i].Tags = append(todos[i].Tags, name)
+		}
+	}
+	return todos, tagRows.Err()
+}
+
+func (s *sqliteStore) Save(todos []Todo) error {
+	return s.inTx(func(tx *sql.Tx) error { return replaceTodos(tx, todos) })
+}
+
+func (s *sqliteStore) Put(todo Todo) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		var id int64
+		err := tx.QueryRow("SELECT id FROM todos WHERE uid = ?", todo.ID).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			var pos int64
+			if err := tx.QueryRow("SELECT COALESCE(MAX(position), 0) + 1 FROM todos").Scan(&pos); err != nil {
+				return err
+			}
+			return insertTodo(tx, pos, todo)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE todos SET parent_uid = ?, recurrence = ?, notes = ?, text = ?, priority = ?, due_date = ?, done = ?,
+			created_at = ?, updated_at = ?, completed_at = ? WHERE id = ?`,
+			todo.ParentID, todo.Recurrence, todo.Notes, todo.Text, todo.Priority, todo.DueDate, todo.Done,
+			formatTime(todo.CreatedAt), formatTime(todo.UpdatedAt), formatTime(todo.CompletedAt), id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM todo_tags WHERE todo_id = ?", id); err != nil {
+			return err
+		}
+		if err := insertTags(tx, id, todo.Tags); err != nil {
+			return err
+		}
+		return pruneTags(tx)
+	})
+}
+
+func (s *sqliteStore) Delete(id string) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec("DELETE FROM todos WHERE uid = ?", id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return fmt.Errorf("delete todo: no todo with ID %s", id)
+		}
+		return pruneTags(tx)
+	})
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *sqliteStore) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// replaceTodos replaces all rows with todos, in order.
+func replaceTodos(tx *sql.Tx, todos []Todo) error {
+	if _, err := tx.Exec("DELETE FROM todos"); err != nil {
+		return err
+	}
+	for i, t := range todos {
+		if err := insertTodo(tx, int64(i+1), t); err != nil {
+			return err
+		}
+	}
+	return pruneTags(tx)
+}
+
+func insertTodo(tx *sql.Tx, position int64, t Todo) error {
+	res, err := tx.Exec(`INSERT INTO todos (position, uid, parent_uid, recurrence, notes, text, priority, due_date, done, created_at, updated_at, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		position, t.ID, t.ParentID, t.Recurrence, t.Notes, t.Text, t.Priority, t.DueDate, t.Done,
+		formatTime(t.CreatedAt), formatTime(t.UpdatedAt), formatTime(t.CompletedAt))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	return insertTags(tx, id, t.Tags)
+}
+
+func insertTags(tx *sql.Tx, todoID int64, tags []string) error {
+	for i, name := range tags {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO tags (name) VALUES (?)", name); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO todo_tags (todo_id, tag_id, position)
+			SELECT ?, id, ? FROM tags WHERE name = ?`, todoID, i, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneTags drops tags no todo refers to anymore.
+func pruneTags(tx *sql.Tx) error {
+	_, err := tx.Exec("DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM todo_tags)")
+	return err
+}
+
+// formatTime stores a timestamp as RFC 3339 text, or "" for the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	s, err := openSQLiteStore(filepath.Join(t.TempDir(), "todolist.db"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	todos := sampleTodos()
+	if err := s.Save(todos); err != nil {
+		t.Fatal(err)
+	}
+	changed := todos[1]
+	changed.Text = "Call the bank again"
+	changed.Tags = []string{"phone"}
+	added := newTodo("Buy stamps")
+	for _, td := range []Todo{changed, added} {
+		if err := s.Put(td); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Delete(todos[2].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(todos[2].ID); err == nil {
+		t.Error("deleting a missing todo succeeded")
+	}
+	want := []Todo{todos[0], changed, todos[3], added}
+	got, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(summaries(got), summaries(want)) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(summaries(got), "\n"), strings.Join(summaries(want), "\n"))
+	}
+}
+
+func TestSQLiteStoreSeeding(t *testing.T) {
+	dir := t.TempDir()
+	db, legacy := filepath.Join(dir, "todolist.db"), filepath.Join(dir, "todolist.txt")
+
+	// A todo file that cannot be read fails the open and leaves nothing
+	// behind, so the import is tried again.
+	if err := os.Mkdir(legacy, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := openSQLiteStore(db, legacy); err == nil {
+		s.Close()
+		t.Fatal("opening with an unreadable todo file succeeded")
+	}
+	if err := os.Remove(legacy); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveTodos(legacy, sampleTodos()); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		s, err := openSQLiteStore(db, legacy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.Load()
+		s.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(summaries(got), summaries(sampleTodos())) {
+			t.Errorf("got\n%s\nwant\n%s", strings.Join(summaries(got), "\n"), strings.Join(summaries(sampleTodos()), "\n"))
+		}
+	}
+}
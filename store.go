@@ -0,0 +1,54 @@
+package main
+
+// Store persists a todo list. Load and Save work on the whole list, while
+// Put and Delete touch a single item so backends that can update in place
+// don't have to rewrite everything on each change.
+type Store interface {
+	// Load returns the whole list. It may return todos together with a
+	// *skippedLinesError when part of the stored data could not be read.
+	Load() ([]Todo, error)
+	Save(todos []Todo) error
+	// Put replaces the todo with the same ID, or appends it if there is none.
+	Put(todo Todo) error
+	Delete(id string) error
+	Close() error
+}
+
+// memoryStore keeps todos in memory only. It is handy for trying out the
+// TUI without touching the file system.
+type memoryStore struct {
+	todos []Todo
+}
+
+func newMemoryStore(todos ...Todo) *memoryStore {
+	todos = append([]Todo(nil), todos...)
+	backfillTodos(todos)
+	return &memoryStore{todos: todos}
+}
+
+func (s *memoryStore) Load() ([]Todo, error) {
+	return append([]Todo(nil), s.todos...), nil
+}
+
+func (s *memoryStore) Save(todos []Todo) error {
+	s.todos = append([]Todo(nil), todos...)
+	return nil
+}
+
+func (s *memoryStore) Put(todo Todo) error {
+	s.todos = putTodo(s.todos, todo)
+	return nil
+}
+
+func (s *memoryStore) Delete(id string) error {
+	todos, err := deleteTodo(s.todos, id)
+	if err != nil {
+		return err
+	}
+	s.todos = todos
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
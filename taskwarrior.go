@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Taskwarrior's `task export` writes a JSON array of tasks, and `task
+// import` reads one back. A ULID and a UUID are both 128 bits, so a todo's
+// ID is written as the UUID with the same bits and read back the same way:
+// a task keeps its uuid through go-do-it and a todo keeps its ID through
+// Taskwarrior. Priorities map as urgent H, medium M and low L, annotations
+// become lines of the notes, and a subtask is written as a dependency of
+// its parent. Deleted tasks and the templates of recurring ones are
+// skipped on import.
+
+const taskwarriorTimeLayout = "20060102T150405Z"
+
+type taskwarriorTask struct {
+	UUID        string                  `json:"uuid"`
+	Description string                  `json:"description"`
+	Status      string                  `json:"status"`
+	Entry       string                  `json:"entry,omitempty"`
+	Modified    string                  `json:"modified,omitempty"`
+	End         string                  `json:"end,omitempty"`
+	Due         string                  `json:"due,omitempty"`
+	Priority    string                  `json:"priority,omitempty"`
+	Tags        []string                `json:"tags,omitempty"`
+	Annotations []taskwarriorAnnotation `json:"annotations,omitempty"`
+	Depends     taskwarriorDepends      `json:"depends,omitempty"`
+}
+
+type taskwarriorAnnotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+// taskwarriorDepends reads depends both as an array of uuids, as
+// Taskwarrior 2.6 and later write it, and as the comma-separated string
+// older versions use.
+type taskwarriorDepends []string
+
+func (d *taskwarriorDepends) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*d = nil
+		for _, uuid := range strings.Split(s, ",") {
+			if uuid = strings.TrimSpace(uuid); uuid != "" {
+				*d = append(*d, uuid)
+			}
+		}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(d))
+}
+
+// idToUUID returns the UUID with the same 128 bits as a ULID. IDs that are
+// not ULIDs get a name-based (version 5 style) UUID instead, which is
+// stable but does not turn back into the same ID.
+func idToUUID(id string) string {
+	b, ok := decodeULID(id)
+	if !ok {
+		sum := sha1.Sum([]byte(id))
+		copy(b[:], sum[:16])
+		b[6] = b[6]&0x0f | 0x50
+		b[8] = b[8]&0x3f | 0x80
+	}
+	h := hex.EncodeToString(b[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// uuidToID undoes idToUUID for any UUID.
+func uuidToID(uuid string) (string, bool) {
+	var b [16]byte
+	h := strings.ReplaceAll(uuid, "-", "")
+	if len(h) != 32 || len(uuid) != 36 {
+		return "", false
+	}
+	if _, err := hex.Decode(b[:], []byte(h)); err != nil {
+		return "", false
+	}
+	return encodeULID(b), true
+}
+
+func taskwarriorPriority(priority string) string {
+	switch priority {
+	case "urgent":
+		return "H"
+	case "low":
+		return "L"
+	}
+	return "M"
+}
+
+func priorityFromTaskwarrior(p string) (string, error) {
+	switch strings.ToUpper(p) {
+	case "H":
+		return "urgent", nil
+	case "M", "":
+		return "medium", nil
+	case "L":
+		return "low", nil
+	}
+	return "", fmt.Errorf("bad priority %q (want H, M or L)", p)
+}
+
+func taskwarriorTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(taskwarriorTimeLayout)
+}
+
+func writeTaskwarrior(w io.Writer, todos []Todo, _ exportOptions) error {
+	// Taskwarrior has no subtasks, but a parent that depends on its
+	// subtasks is blocked until they are done, which is close.
+	depends := map[string][]string{}
+	for _, t := range todos {
+		if t.ParentID != "" {
+			depends[t.ParentID] = append(depends[t.ParentID], idToUUID(t.ID))
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("[\n")
+	for i, t := range todos {
+		task := taskwarriorTask{
+			UUID:        idToUUID(t.ID),
+			Description: strings.Join(strings.Fields(t.Text), " "),
+			Status:      "pending",
+			Entry:       taskwarriorTime(t.CreatedAt),
+			Modified:    taskwarriorTime(t.UpdatedAt),
+			Priority:    taskwarriorPriority(t.Priority),
+			Tags:        taskwarriorTags(t.Tags),
+			Depends:     depends[t.ID],
+		}
+		if t.Done {
+			task.Status = "completed"
+			end := t.CompletedAt
+			if end.IsZero() {
+				end = t.UpdatedAt
+			}
+			task.End = taskwarriorTime(end)
+		}
+		if due, err := time.ParseInLocation(dateLayout, t.DueDate, time.Local); err == nil {
+			task.Due = taskwarriorTime(due)
+		}
+		// Taskwarrior tells annotations apart by their entry time, so each
+		// line gets its own second after the todo was created.
+		for n, line := range strings.Split(t.Notes, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				entry := t.CreatedAt.Add(time.Duration(n) * time.Second)
+				task.Annotations = append(task.Annotations, taskwarriorAnnotation{taskwarriorTime(entry), line})
+			}
+		}
+		b, err := json.Marshal(task)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		if i < len(todos)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// taskwarriorTags drops spaces from tags, which Taskwarrior does not allow.
+func taskwarriorTags(tags []string) []string {
+	var out []string
+	for _, tag := range tags {
+		if tag = strings.Join(strings.Fields(tag), "_"); tag != "" {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// readTaskwarrior reads a JSON array of tasks, or one task per line as
+// Taskwarrior 2.5 and earlier export them.
+func readTaskwarrior(r io.Reader, _ importOptions) ([]Todo, []badLine, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	dec := json.NewDecoder(bytes.NewReader(data))
+	array := false
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if _, err := dec.Token(); err != nil {
+			return nil, nil, err
+		}
+		array = true
+	}
+	lineAt := func(offset int64) int {
+		// The decoder stops just before the next value, so skip the
+		// separators to the line the task starts on.
+		for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,", data[offset]) >= 0 {
+			offset++
+		}
+		return 1 + bytes.Count(data[:offset], []byte("\n"))
+	}
+
+	var tasks []taskwarriorTask
+	var lines []int
+	for dec.More() {
+		line := lineAt(dec.InputOffset())
+		var task taskwarriorTask
+		if err := dec.Decode(&task); err != nil {
+			return nil, nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		tasks = append(tasks, task)
+		lines = append(lines, line)
+	}
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// A task that exactly one other task depends on is taken to be its
+	// subtask, the way writeTaskwarrior writes them.
+	dependents := map[string][]string{}
+	for _, task := range tasks {
+		for _, dep := range task.Depends {
+			dependents[strings.ToLower(dep)] = append(dependents[strings.ToLower(dep)], task.UUID)
+		}
+	}
+
+	var todos []Todo
+	var bad []badLine
+	for i, task := range tasks {
+		if task.Status == "deleted" || task.Status == "recurring" {
+			continue
+		}
+		t, err := taskwarriorTodo(task)
+		if err == nil {
+			if parents := dependents[strings.ToLower(task.UUID)]; len(parents) == 1 {
+				t.ParentID, _ = uuidToID(parents[0])
+			}
+			todos = append(todos, t)
+			continue
+		}
+		bad = append(bad, badLine{line: lines[i], text: task.Description, err: err})
+	}
+	return todos, bad, nil
+}
+
+// taskwarriorTodo converts one task, checking each value.
+func taskwarriorTodo(task taskwarriorTask) (Todo, error) {
+	var t Todo
+	var ok bool
+	if t.ID, ok = uuidToID(task.UUID); !ok {
+		return t, fmt.Errorf("bad uuid %q", task.UUID)
+	}
+	if t.Text = strings.Join(strings.Fields(task.Description), " "); t.Text == "" {
+		return t, fmt.Errorf("empty description")
+	}
+	var err error
+	if t.Priority, err = priorityFromTaskwarrior(task.Priority); err != nil {
+		return t, err
+	}
+	switch task.Status {
+	case "pending", "waiting", "":
+	case "completed":
+		t.Done = true
+	default:
+		return t, fmt.Errorf("unknown status %q", task.Status)
+	}
+	t.Tags = task.Tags
+	for _, ts := range []struct {
+		name  string
+		value string
+		dst   *time.Time
+	}{{"entry", task.Entry, &t.CreatedAt}, {"modified", task.Modified, &t.UpdatedAt}, {"end", task.End, &t.CompletedAt}} {
+		if ts.value == "" {
+			continue
+		}
+		if *ts.dst, err = time.Parse(taskwarriorTimeLayout, ts.value); err != nil {
+			return t, fmt.Errorf("bad %s time %q", ts.name, ts.value)
+		}
+	}
+	if !t.Done {
+		t.CompletedAt = time.Time{}
+	}
+	if task.Due != "" {
+		due, err := time.Parse(taskwarriorTimeLayout, task.Due)
+		if err != nil {
+			return t, fmt.Errorf("bad due time %q", task.Due)
+		}
+		t.DueDate = due.Local().Format(dateLayout)
+	}
+	var notes []string
+	for _, a := range task.Annotations {
+		notes = append(notes, a.Description)
+	}
+	t.Notes = strings.Join(notes, "\n")
+	return t, nil
+}
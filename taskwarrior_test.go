@@ -0,0 +1,122 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIDToUUID(t *testing.T) {
+	tests := []struct {
+		id, uuid string
+	}{
+		{"00000000000000000000000000", "00000000-0000-0000-0000-000000000000"},
+		{"7ZZZZZZZZZZZZZZZZZZZZZZZZZ", "ffffffff-ffff-ffff-ffff-ffffffffffff"},
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAV", "01563e3a-b5d3-d676-4c61-efb99302bd5b"},
+		{"01JA00000000000000000000A1", "01928000-0000-0000-0000-000000000141"},
+	}
+	for _, tt := range tests {
+		if got := idToUUID(tt.id); got != tt.uuid {
+			t.Errorf("idToUUID(%s) = %s, want %s", tt.id, got, tt.uuid)
+		}
+		if got := idToUUID(strings.ToLower(tt.id)); got != tt.uuid {
+			t.Errorf("idToUUID(%s) = %s, want %s", strings.ToLower(tt.id), got, tt.uuid)
+		}
+		if got, ok := uuidToID(tt.uuid); !ok || got != tt.id {
+			t.Errorf("uuidToID(%s) = %s, %v; want %s", tt.uuid, got, ok, tt.id)
+		}
+		if got, ok := uuidToID(strings.ToUpper(tt.uuid)); !ok || got != tt.id {
+			t.Errorf("uuidToID(%s) = %s, %v; want %s", strings.ToUpper(tt.uuid), got, ok, tt.id)
+		}
+	}
+}
+
+func TestIDToUUIDNotULID(t *testing.T) {
+	for _, id := range []string{"", "1", "abc@example.com", "8ZZZZZZZZZZZZZZZZZZZZZZZZZ", "01ARZ3NDEKTSV4RRFFQ69G5FAU"} {
+		uuid := idToUUID(id)
+		if uuid != idToUUID(id) {
+			t.Errorf("idToUUID(%q) is not stable", id)
+		}
+		if len(uuid) != 36 || uuid[14] != '5' || !strings.ContainsRune("89ab", rune(uuid[19])) {
+			t.Errorf("idToUUID(%q) = %s, want a version 5 style UUID", id, uuid)
+		}
+		if _, ok := uuidToID(uuid); !ok {
+			t.Errorf("uuidToID(%s) failed", uuid)
+		}
+	}
+}
+
+func TestUUIDToIDErrors(t *testing.T) {
+	for _, uuid := range []string{"", "01563e3a-b5d3-d676-4c61-efb99302bd5", "01563e3ab5d3d6764c61efb99302bd5b", "g1563e3a-b5d3-d676-4c61-efb99302bd5b"} {
+		if id, ok := uuidToID(uuid); ok {
+			t.Errorf("uuidToID(%q) = %s, want failure", uuid, id)
+		}
+	}
+}
+
+func TestTaskwarriorRoundTrip(t *testing.T) {
+	todos := sampleTodos()
+	todos[1].Tags = []string{"home", "phone calls"}
+	// Taskwarrior's recurrence works differently and is not carried over,
+	// and tags cannot hold spaces.
+	want := sampleTodos()
+	want[1].Tags = []string{"home", "phone_calls"}
+	for i := range want {
+		want[i].Recurrence = ""
+	}
+	_, got := roundTrip(t, "taskwarrior", todos, exportOptions{})
+	if !slices.Equal(summaries(got), summaries(want)) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(summaries(got), "\n"), strings.Join(summaries(want), "\n"))
+	}
+	for i, td := range got {
+		if !td.CreatedAt.Equal(todos[i].CreatedAt) || !td.UpdatedAt.Equal(todos[i].UpdatedAt) || !td.CompletedAt.Equal(todos[i].CompletedAt) {
+			t.Errorf("todo %d: times %v %v %v, want %v %v %v", i, td.CreatedAt, td.UpdatedAt, td.CompletedAt,
+				todos[i].CreatedAt, todos[i].UpdatedAt, todos[i].CompletedAt)
+		}
+	}
+}
+
+func TestReadTaskwarrior(t *testing.T) {
+	// One task per line, as Taskwarrior 2.5 exports them.
+	input := `{"uuid":"01563e3a-b5d3-d676-4c61-efb99302bd5b","description":"Plan  trip","status":"pending","priority":"H","tags":["travel"],"depends":"01928000-0000-0000-0000-000000000141,01928000-0000-0000-0000-000000000142"}
+{"uuid":"01928000-0000-0000-0000-000000000141","description":"Book","status":"completed","end":"20261010T120000Z","due":"20261101T000000Z"}
+{"uuid":"01928000-0000-0000-0000-000000000142","description":"Pack","status":"waiting","priority":"X"}
+{"uuid":"01928000-0000-0000-0000-000000000143","description":"Gone","status":"deleted"}
+{"uuid":"01928000-0000-0000-0000-000000000144","description":"Template","status":"recurring"}
+{"uuid":"not-a-uuid","description":"Bad","status":"pending"}
+{"uuid":"01928000-0000-0000-0000-000000000145","description":"Odd","status":"someday"}
+{"uuid":"01928000-0000-0000-0000-000000000146","description":"Shared","status":"pending"}
+{"uuid":"01928000-0000-0000-0000-000000000147","description":"A","status":"pending","depends":["01928000-0000-0000-0000-000000000146"]}
+{"uuid":"01928000-0000-0000-0000-000000000148","description":"B","status":"pending","depends":["01928000-0000-0000-0000-000000000146"]}
+`
+	got, bad := readString(t, "taskwarrior", input)
+	want := []string{
+		`01ARZ3NDEKTSV4RRFFQ69G5FAV [ ] "Plan trip" urgent due= tags=[travel] parent= rec=`,
+		`01JA00000000000000000000A1 [x] "Book" medium due=` + got[1].DueDate + ` tags=[] parent=01ARZ3NDEKTSV4RRFFQ69G5FAV rec=`,
+		`01JA00000000000000000000A6 [ ] "Shared" medium due= tags=[] parent= rec=`,
+		`01JA00000000000000000000A7 [ ] "A" medium due= tags=[] parent= rec=`,
+		`01JA00000000000000000000A8 [ ] "B" medium due= tags=[] parent= rec=`,
+	}
+	if !slices.Equal(summaries(got), want) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(summaries(got), "\n"), strings.Join(want, "\n"))
+	}
+	var lines []int
+	for _, b := range bad {
+		lines = append(lines, b.line)
+	}
+	if want := []int{3, 6, 7}; !slices.Equal(lines, want) {
+		t.Errorf("rejected lines %v, want %v", lines, want)
+	}
+}
+
+func TestReadTaskwarriorErrors(t *testing.T) {
+	for _, input := range []string{
+		`[{"uuid":"01928000-0000-0000-0000-000000000141","description":"x"}`,
+		`{"uuid":1}`,
+		`not json`,
+	} {
+		if todos, _, err := readTaskwarrior(strings.NewReader(input), importOptions{}); err == nil {
+			t.Errorf("readTaskwarrior(%q) = %v, want an error", input, todos)
+		}
+	}
+}
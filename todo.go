@@ -3,43 +3,282 @@ package main
 import (
 	"bufio"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 const todoFile = "todolist.txt"
 
-func loadTodos() []Todo {
-	f, err := os.Open(todoFile)
+// lockMode says what to do when another process holds the todo file lock.
+type lockMode int
+
+const (
+	lockFail lockMode = iota
+	lockWait
+	lockReadOnly
+)
+
+func parseLockMode(s string) (lockMode, error) {
+	switch s {
+	case "fail":
+		return lockFail, nil
+	case "wait":
+		return lockWait, nil
+	case "readonly":
+		return lockReadOnly, nil
+	}
+	return 0, fmt.Errorf("unknown lock mode %q (want fail, wait or readonly)", s)
+}
+
+var (
+	errLocked   = errors.New("locked by another process")
+	errReadOnly = errors.New("todo list is open read-only")
+)
+
+// lockOptions say how a todo file is locked: what to do when another
+// process holds the lock, how long to wait for it, and whether this process
+// is the TUI, which holds the lock for as long as it runs.
+type lockOptions struct {
+	onLocked lockMode
+	timeout  time.Duration
+	tui      bool
+}
+
+// fileStore is the default Store: one JSON-encoded todo per line. While
+// open it holds an advisory lock on a ".lock" file next to the data file,
+// so two instances never write the same list. The lock file says who holds
+// it, so that others can tell the TUI from a command that will soon be done.
+type fileStore struct {
+	path     string
+	lock     *os.File
+	readOnly bool
+}
+
+func openFileStore(path string, opts lockOptions) (*fileStore, error) {
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []Todo{}
+		return nil, err
+	}
+	s := &fileStore{path: path, lock: lock}
+
+	err = lockFile(lock, false)
+	if errors.Is(err, errLocked) {
+		switch opts.onLocked {
+		case lockWait:
+			err = waitLock(lock, path, opts.timeout)
+		case lockReadOnly:
+			s.readOnly = true
+			lock.Close()
+			s.lock = nil
+			return s, nil
+		default:
+			err = inUseError(lock, path)
 		}
-		log.Fatal(err)
 	}
-	defer f.Close()
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	holder := "command"
+	if opts.tui {
+		holder = "tui"
+	}
+	if err := lock.Truncate(0); err == nil {
+		fmt.Fprintf(lock, "%d %s\n", os.Getpid(), holder)
+	}
+	return s, nil
+}
+
+// waitLock takes the lock on f once another process releases it, waiting
+// up to timeout, or for as long as it takes if timeout is 0. Commands hold
+// the lock only for a moment, but the TUI holds it until it quits, so
+// waitLock gives up at once when the TUI has it.
+func waitLock(f *os.File, path string, timeout time.Duration) error {
+	if _, tui := lockHolder(f); tui {
+		return inUseError(f, path)
+	}
+	fmt.Fprintf(os.Stderr, "Waiting for another go-do-it to release %s...\n", path)
+	if timeout <= 0 {
+		return lockFile(f, true)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		err := lockFile(f, false)
+		if !errors.Is(err, errLocked) {
+			return err
+		}
+		if _, tui := lockHolder(f); tui {
+			return inUseError(f, path)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s is still in use by another go-do-it after %s", path, timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// lockHolder reads who holds the lock on f, as recorded by openFileStore.
+func lockHolder(f *os.File) (pid int, tui bool) {
+	b := make([]byte, 64)
+	n, _ := f.ReadAt(b, 0)
+	var holder string
+	if _, err := fmt.Sscan(string(b[:n]), &pid, &holder); err != nil {
+		return 0, false
+	}
+	return pid, holder == "tui"
+}
+
+// inUseError says that path is locked, and by the TUI if it is.
+func inUseError(f *os.File, path string) error {
+	if pid, tui := lockHolder(f); tui {
+		return fmt.Errorf("%s is open in the go-do-it TUI (pid %d); quit it and try again", path, pid)
+	}
+	return fmt.Errorf("%s is in use by another go-do-it process", path)
+}
 
-	var todos []Todo
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
+// Load reads the list. Files in an older format are upgraded and rewritten
+// straight away, so IDs given to old todos stick. Lines that cannot be read
+// are moved to a ".rejected" file next to the list and reported with a
+// *skippedLinesError alongside the todos that were read.
+func (s *fileStore) Load() ([]Todo, error) {
+	todos, skipped, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	if len(skipped) > 0 {
+		e := &skippedLinesError{path: s.path, lines: skipped}
+		if !s.readOnly {
+			e.rejected = s.path + ".rejected"
 		}
-		var todo Todo
-		if err := json.Unmarshal([]byte(line), &todo); err == nil {
-			todos = append(todos, todo)
-		} else {
-			// fallback: try to parse old format (if any)
-			todo = parseLegacyTodo(line)
-			todos = append(todos, todo)
+		return todos, e
+	}
+	return todos, nil
+}
+
+func (s *fileStore) load() ([]Todo, []badLine, error) {
+	todos, outdated, skipped, err := loadTodos(s.path)
+	if err != nil || s.readOnly || (!outdated && len(skipped) == 0) {
+		return todos, skipped, err
+	}
+	if len(skipped) > 0 {
+		if err := writeRejected(s.path+".rejected", skipped); err != nil {
+			return nil, nil, err
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
+	return todos, skipped, saveTodos(s.path, todos)
+}
+
+func (s *fileStore) Save(todos []Todo) error {
+	if s.readOnly {
+		return errReadOnly
 	}
-	return todos
+	return saveTodos(s.path, todos)
+}
+
+func (s *fileStore) Put(todo Todo) error {
+	todos, _, err := s.load()
+	if err != nil {
+		return err
+	}
+	return s.Save(putTodo(todos, todo))
+}
+
+func (s *fileStore) Delete(id string) error {
+	todos, _, err := s.load()
+	if err != nil {
+		return err
+	}
+	if todos, err = deleteTodo(todos, id); err != nil {
+		return err
+	}
+	return s.Save(todos)
+}
+
+// ReadOnly reports whether the store was opened without the lock.
+func (s *fileStore) ReadOnly() bool {
+	return s.readOnly
+}
+
+func (s *fileStore) Close() error {
+	if s.lock == nil {
+		return nil
+	}
+	s.lock.Truncate(0)
+	unlockFile(s.lock)
+	err := s.lock.Close()
+	s.lock = nil
+	return err
+}
+
+// putTodo replaces the todo with todo's ID, or appends todo.
+func putTodo(todos []Todo, todo Todo) []Todo {
+	if i := indexOfTodo(todos, todo.ID); i >= 0 {
+		todos[i] = todo
+		return todos
+	}
+	return append(todos, todo)
+}
+
+func deleteTodo(todos []Todo, id string) ([]Todo, error) {
+	i := indexOfTodo(todos, id)
+	if i < 0 {
+		return todos, fmt.Errorf("delete todo: no todo with ID %s", id)
+	}
+	return append(todos[:i], todos[i+1:]...), nil
+}
+
+func indexOfTodo(todos []Todo, id string) int {
+	for i, t := range todos {
+		if t.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// backfillTodos gives todos saved before IDs and timestamps existed an ID
+// and sets missing timestamps to now. It reports whether anything changed.
+func backfillTodos(todos []Todo) bool {
+	now := time.Now()
+	changed := false
+	for i := range todos {
+		t := &todos[i]
+		if t.ID == "" {
+			t.ID = newID()
+			changed = true
+		}
+		if t.CreatedAt.IsZero() {
+			t.CreatedAt = now
+			changed = true
+		}
+		if t.UpdatedAt.IsZero() {
+			t.UpdatedAt = t.CreatedAt
+			changed = true
+		}
+		if t.Done && t.CompletedAt.IsZero() {
+			t.CompletedAt = t.UpdatedAt
+			changed = true
+		}
+	}
+	return changed
+}
+
+// loadTodos reads a todo file of any format version. It also reports
+// whether the file is in an older format and the lines it had to skip.
+func loadTodos(path string) (todos []Todo, outdated bool, skipped []badLine, err error) {
+	version, records, err := readRecords(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Todo{}, false, nil, nil
+		}
+		return nil, false, nil, err
+	}
+	todos, skipped, err = decodeRecords(version, records)
+	return todos, version < formatVersion, skipped, err
 }
 
 // parseLegacyTodo tries to parse a legacy todo string into a Todo struct
@@ -72,23 +311,56 @@ func parseLegacyTodo(line string) Todo {
 	todo.Text = strings.TrimSpace(line)
 	return todo
 }
-func saveTodos(todos []Todo) {
-	f, err := os.Create(todoFile)
+
+// saveTodos writes todos to a temporary file next to path, syncs it and
+// renames it into place, so a crash mid-write never leaves a truncated list.
+func saveTodos(path string, todos []Todo) error {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
 	defer f.Close()
 
+	perm := os.FileMode(0o644)
+	if fi, err := os.Stat(path); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+
 	writer := bufio.NewWriter(f)
+	header, err := json.Marshal(fileHeader{Format: formatName, Version: formatVersion})
+	if err != nil {
+		return err
+	}
+	if _, err := writer.WriteString(string(header) + "\n"); err != nil {
+		return err
+	}
 	for _, t := range todos {
 		b, err := json.Marshal(t)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 		_, err = writer.WriteString(string(b) + "\n")
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 	}
-	writer.Flush()
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(dir)
 }
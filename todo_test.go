@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todolist.txt")
+	s, err := openFileStore(path, lockOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	todos := sampleTodos()
+	if err := s.Save(todos); err != nil {
+		t.Fatal(err)
+	}
+	changed := todos[1]
+	changed.Text = "Call the bank again"
+	added := newTodo("Buy stamps")
+	for _, td := range []Todo{changed, added} {
+		if err := s.Put(td); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Delete(todos[2].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(todos[2].ID); err == nil {
+		t.Error("deleting a missing todo succeeded")
+	}
+	want := []Todo{todos[0], changed, todos[3], added}
+	got, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(summaries(got), summaries(want)) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(summaries(got), "\n"), strings.Join(summaries(want), "\n"))
+	}
+	// Saves go through a temporary file that is renamed into place.
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp") {
+			t.Errorf("temporary file %s left behind", e.Name())
+		}
+	}
+}
+
+func TestFileStoreLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todolist.txt")
+	first, err := openFileStore(path, lockOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err := openFileStore(path, lockOptions{onLocked: lockFail}); err == nil {
+		s.Close()
+		t.Fatal("second open of a locked list succeeded")
+	}
+
+	ro, err := openFileStore(path, lockOptions{onLocked: lockReadOnly})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ro.Load(); err != nil {
+		t.Errorf("read-only Load: %v", err)
+	}
+	if err := ro.Save(nil); !errors.Is(err, errReadOnly) {
+		t.Errorf("read-only Save = %v, want %v", err, errReadOnly)
+	}
+	ro.Close()
+
+	start := time.Now()
+	if s, err := openFileStore(path, lockOptions{onLocked: lockWait, timeout: 300 * time.Millisecond}); err == nil {
+		s.Close()
+		t.Fatal("waiting for a lock that is never released succeeded")
+	}
+	if d := time.Since(start); d < 300*time.Millisecond {
+		t.Errorf("gave up after %s, before the timeout", d)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		first.Close()
+	}()
+	second, err := openFileStore(path, lockOptions{onLocked: lockWait, timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("waiting for a released lock: %v", err)
+	}
+	second.Close()
+}
+
+func TestFileStoreLockHeldByTUI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todolist.txt")
+	tui, err := openFileStore(path, lockOptions{tui: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tui.Close()
+
+	// A command does not wait for the TUI, which may run for hours.
+	start := time.Now()
+	s, err := openFileStore(path, lockOptions{onLocked: lockWait, timeout: 5 * time.Second})
+	if err == nil {
+		s.Close()
+		t.Fatal("a command got the lock the TUI holds")
+	}
+	if !strings.Contains(err.Error(), "TUI") {
+		t.Errorf("error %q does not mention the TUI", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("took %s to give up", d)
+	}
+}
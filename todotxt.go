@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The todo.txt format (https://github.com/todotxt/todo.txt) keeps one todo
+// per line:
+//
+//	x 2024-06-02 2024-05-30 Pay rent +home @bank due:2024-06-01 id:01J9Z...
+//	(A) 2024-05-30 Call mom @phone rec:1w
+//
+// "x" marks it done, followed by the completion and creation dates. An open
+// todo starts with its priority and creation date. +project and @context
+// words become tags; @contexts keep their "@" so they are written back the
+// same way. Of the key:value pairs, due:, rec:, id: and p: (the parent's
+// id, as topydo uses it) map onto Todo fields and pri: keeps the priority
+// of done todos; any others stay in the text.
+
+var todoTxtDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// todoTxtPriority maps todo.txt's (A)-(Z) onto our priorities. Medium is
+// the default, so todoTxtLetter writes it without one.
+func todoTxtPriority(letter string) string {
+	switch letter {
+	case "A":
+		return "urgent"
+	case "B":
+		return "medium"
+	}
+	return "low"
+}
+
+func todoTxtLetter(priority string) string {
+	switch priority {
+	case "urgent":
+		return "A"
+	case "low":
+		return "C"
+	}
+	return ""
+}
+
+func readTodoTxt(r io.Reader, _ importOptions) ([]Todo, []badLine, error) {
+	var todos []Todo
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		todos = append(todos, parseTodoTxt(sc.Text()))
+	}
+	return todos, nil, sc.Err()
+}
+
+// parseTodoTxt reads one todo.txt line.
+func parseTodoTxt(line string) Todo {
+	t := Todo{Priority: "medium"}
+	words := strings.Fields(line)
+	date := func() (time.Time, bool) {
+		if len(words) == 0 || !todoTxtDate.MatchString(words[0]) {
+			return time.Time{}, false
+		}
+		d, err := time.ParseInLocation(dateLayout, words[0], time.Local)
+		if err != nil {
+			return time.Time{}, false
+		}
+		words = words[1:]
+		return d, true
+	}
+	priority := func() {
+		if len(words) > 0 && len(words[0]) == 3 && words[0][0] == '(' && words[0][2] == ')' &&
+			words[0][1] >= 'A' && words[0][1] <= 'Z' {
+			t.Priority = todoTxtPriority(words[0][1:2])
+			words = words[1:]
+		}
+	}
+
+	if len(words) > 0 && words[0] == "x" {
+		t.Done = true
+		words = words[1:]
+		priority()
+		if completed, ok := date(); ok {
+			t.CompletedAt = completed
+			if created, ok := date(); ok {
+				t.CreatedAt = created
+			}
+		}
+	} else {
+		priority()
+		if created, ok := date(); ok {
+			t.CreatedAt = created
+		}
+	}
+
+	var text []string
+	for _, w := range words {
+		switch {
+		case len(w) > 1 && w[0] == '+':
+			t.Tags = append(t.Tags, w[1:])
+			continue
+		case len(w) > 1 && w[0] == '@':
+			t.Tags = append(t.Tags, w)
+			continue
+		}
+		if key, value, ok := strings.Cut(w, ":"); ok && value != "" {
+			switch key {
+			case "due":
+				if todoTxtDate.MatchString(value) {
+					t.DueDate = value
+					continue
+				}
+			case "rec":
+				if rule, err := parseTodoTxtRec(value); err == nil {
+					t.Recurrence = rule
+					continue
+				}
+			case "id":
+				t.ID = value
+				continue
+			case "p":
+				t.ParentID = value
+				continue
+			case "pri":
+				if len(value) == 1 && value[0] >= 'A' && value[0] <= 'Z' {
+					t.Priority = todoTxtPriority(value)
+					continue
+				}
+			}
+		}
+		text = append(text, w)
+	}
+	t.Text = strings.Join(text, " ")
+	if !t.CreatedAt.IsZero() {
+		t.UpdatedAt = t.CreatedAt
+	}
+	if t.CompletedAt.After(t.UpdatedAt) {
+		t.UpdatedAt = t.CompletedAt
+	}
+	return t
+}
+
+// parseTodoTxtRec reads a rec: value from the todo.txt recurrence add-on,
+// such as "1w", "+3d" or "2b" (business days), or a full RRULE.
+func parseTodoTxtRec(s string) (string, error) {
+	if strings.Contains(s, "=") {
+		return recurrenceInput(s)
+	}
+	s = strings.TrimPrefix(s, "+")
+	if len(s) < 2 {
+		return "", fmt.Errorf("bad rec: %q", s)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 1 {
+		return "", fmt.Errorf("bad rec: %q", s)
+	}
+	r := recurrence{interval: n}
+	switch s[len(s)-1] {
+	case 'd':
+		r.freq = "DAILY"
+	case 'b':
+		r.freq = "DAILY"
+		r.days = workWeek
+	case 'w':
+		r.freq = "WEEKLY"
+	case 'm':
+		r.freq = "MONTHLY"
+	case 'y':
+		r.freq = "YEARLY"
+	default:
+		return "", fmt.Errorf("bad rec: %q", s)
+	}
+	return r.String(), nil
+}
+
+// todoTxtRec writes a recurrence as a rec: value, in the add-on's short
+// form when it has one.
+func todoTxtRec(rule string) string {
+	r, err := parseRecurrence(rule)
+	if err != nil {
+		return rule
+	}
+	if r.month != 0 || r.monthDay != 0 || !r.until.IsZero() || r.count > 0 {
+		return r.String()
+	}
+	unit := map[string]string{"DAILY": "d", "WEEKLY": "w", "MONTHLY": "m", "YEARLY": "y"}[r.freq]
+	if r.freq == "DAILY" && slices.Equal(r.days, workWeek) {
+		unit = "b"
+	} else if len(r.days) > 0 {
+		return r.String()
+	}
+	return fmt.Sprintf("+%d%s", r.interval, unit)
+}
+
+func writeTodoTxt(w io.Writer, todos []Todo, _ exportOptions) error {
+	bw := bufio.NewWriter(w)
+	for _, t := range todos {
+		if _, err := bw.WriteString(formatTodoTxt(t) + "\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// formatTodoTxt writes t as one todo.txt line.
+func formatTodoTxt(t Todo) string {
+	var parts []string
+	letter := todoTxtLetter(t.Priority)
+	if t.Done {
+		parts = append(parts, "x")
+		// A completion date may only be given with a creation date.
+		if !t.CompletedAt.IsZero() && !t.CreatedAt.IsZero() {
+			parts = append(parts, t.CompletedAt.Format(dateLayout), t.CreatedAt.Format(dateLayout))
+		}
+	} else {
+		if letter != "" {
+			parts = append(parts, "("+letter+")")
+		}
+		if !t.CreatedAt.IsZero() {
+			parts = append(parts, t.CreatedAt.Format(dateLayout))
+		}
+	}
+	parts = append(parts, strings.Fields(t.Text)...)
+	for _, tag := range t.Tags {
+		tag = strings.Join(strings.Fields(tag), "_")
+		if tag == "" {
+			continue
+		}
+		if !strings.HasPrefix(tag, "@") {
+			tag = "+" + tag
+		}
+		parts = append(parts, tag)
+	}
+	if t.DueDate != "" {
+		parts = append(parts, "due:"+t.DueDate)
+	}
+	if t.Recurrence != "" {
+		parts = append(parts, "rec:"+todoTxtRec(t.Recurrence))
+	}
+	if t.ID != "" {
+		parts = append(parts, "id:"+t.ID)
+	}
+	if t.ParentID != "" {
+		parts = append(parts, "p:"+t.ParentID)
+	}
+	if t.Done && letter != "" {
+		parts = append(parts, "pri:"+letter)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	todos := sampleTodos()
+	todos[1].Tags = []string{"home", "@phone"}
+	out, got := roundTrip(t, "todotxt", todos, exportOptions{})
+	if !slices.Equal(summaries(got), summaries(todos)) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(summaries(got), "\n"), strings.Join(summaries(todos), "\n"))
+	}
+	for i, td := range got {
+		if !td.CreatedAt.Equal(todos[i].CreatedAt) || !td.CompletedAt.Equal(todos[i].CompletedAt) {
+			t.Errorf("todo %d: created %v, completed %v; want %v, %v", i, td.CreatedAt, td.CompletedAt, todos[i].CreatedAt, todos[i].CompletedAt)
+		}
+	}
+	want := "(A) 2026-10-01 Pay rent +home due:2026-11-01 rec:+1m id:01JA00000000000000000000A1\n"
+	if !strings.HasPrefix(out, want) {
+		t.Errorf("first line of\n%s\nwant %s", out, want)
+	}
+}
+
+func TestParseTodoTxt(t *testing.T) {
+	tests := []struct {
+		line string
+		want string // summary of the todo
+	}{
+		{"Buy milk", ` [ ] "Buy milk" medium due= tags=[] parent= rec=`},
+		{"(A) Call mom @phone +family", ` [ ] "Call mom" urgent due= tags=[@phone family] parent= rec=`},
+		{"(B) 2024-05-30 Thing", ` [ ] "Thing" medium due= tags=[] parent= rec=`},
+		{"(D) Thing", ` [ ] "Thing" low due= tags=[] parent= rec=`},
+		{"x 2024-06-02 2024-05-30 Pay rent pri:A", ` [x] "Pay rent" urgent due= tags=[] parent= rec=`},
+		{"x (C) Done already", ` [x] "Done already" low due= tags=[] parent= rec=`},
+		{"Report due:2024-06-01 rec:2w", ` [ ] "Report" medium due=2024-06-01 tags=[] parent= rec=FREQ=WEEKLY;INTERVAL=2`},
+		{"Work rec:+1b", ` [ ] "Work" medium due= tags=[] parent= rec=FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR`},
+		{"Sub id:7 p:3", `7 [ ] "Sub" medium due= tags=[] parent=3 rec=`},
+		// Pairs go-do-it does not know, and ones with bad values, stay text.
+		{"See url:https://example.com due:soon rec:often", ` [ ] "See url:https://example.com due:soon rec:often" medium due= tags=[] parent= rec=`},
+		{"x marks the spot", ` [x] "marks the spot" medium due= tags=[] parent= rec=`},
+		{"xylophone lessons", ` [ ] "xylophone lessons" medium due= tags=[] parent= rec=`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := summary(parseTodoTxt(tt.line)); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTodoTxtRec(t *testing.T) {
+	tests := []struct {
+		rule, want string
+	}{
+		{"FREQ=DAILY", "+1d"},
+		{"FREQ=WEEKLY;INTERVAL=2", "+2w"},
+		{"FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", "+1b"},
+		{"FREQ=YEARLY", "+1y"},
+		{"FREQ=MONTHLY;BYMONTHDAY=31", "FREQ=MONTHLY;BYMONTHDAY=31"},
+		{"FREQ=WEEKLY;BYDAY=MO", "FREQ=WEEKLY;BYDAY=MO"},
+	}
+	for _, tt := range tests {
+		got := todoTxtRec(tt.rule)
+		if got != tt.want {
+			t.Errorf("todoTxtRec(%s) = %s, want %s", tt.rule, got, tt.want)
+		}
+		back, err := parseTodoTxtRec(got)
+		if err != nil || back != tt.rule {
+			t.Errorf("parseTodoTxtRec(%s) = %s, %v; want %s", got, back, err, tt.rule)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import "time"
+
+// childIndex maps each todo ID to the indexes of its children, in list
+// order. Todos whose parent is missing count as top-level, under "".
+func childIndex(todos []Todo) map[string][]int {
+	ids := make(map[string]bool, len(todos))
+	for _, t := range todos {
+		ids[t.ID] = true
+	}
+	children := make(map[string][]int)
+	for i, t := range todos {
+		parent := t.ParentID
+		if !ids[parent] || parent == t.ID {
+			parent = ""
+		}
+		children[parent] = append(children[parent], i)
+	}
+	return children
+}
+
+// buildTree lists todos depth first, each followed by its children, with
+// siblings sorted by key. Children of collapsed todos are left out. It
+// returns indexes into todos and the depth of each.
+func buildTree(todos []Todo, key sortKey, desc bool, collapsed map[string]bool) (rows, depths []int) {
+	children := childIndex(todos)
+	seen := make([]bool, len(todos))
+	// Hidden todos under a collapsed parent are still walked so they are
+	// marked seen and not mistaken for members of a cycle below.
+	var walk func(kids []int, depth int, hidden bool)
+	walk = func(kids []int, depth int, hidden bool) {
+		kids = append([]int(nil), kids...)
+		sortRows(kids, todos, key, desc)
+		for _, i := range kids {
+			if seen[i] {
+				continue
+			}
+			seen[i] = true
+			if !hidden {
+				rows = append(rows, i)
+				depths = append(depths, depth)
+			}
+			id := todos[i].ID
+			walk(children[id], depth+1, hidden || collapsed[id])
+		}
+	}
+	walk(children[""], 0, false)
+	// Todos caught in a parent cycle are never reached from the top level;
+	// show them there rather than losing them.
+	for i := range todos {
+		if !seen[i] {
+			walk([]int{i}, 0, false)
+		}
+	}
+	return rows, depths
+}
+
+// subtree returns the index of the todo with id followed by the indexes of
+// all its descendants, in ascending order.
+func subtree(todos []Todo, id string) []int {
+	in := map[string]bool{id: true}
+	var out []int
+	// Parents usually come before children, but repeat until nothing
+	// changes in case a todo was re-parented under a later one.
+	for changed := true; changed; {
+		changed = false
+		for _, t := range todos {
+			if !in[t.ID] && in[t.ParentID] && t.ParentID != "" {
+				in[t.ID] = true
+				changed = true
+			}
+		}
+	}
+	for i, t := range todos {
+		if in[t.ID] {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// progress counts the done todos among the descendants of id. Like
+// buildTree it visits each todo once, so a parent cycle cannot loop.
+func progress(todos []Todo, children map[string][]int, id string) (done, total int) {
+	seen := map[string]bool{id: true}
+	var walk func(id string)
+	walk = func(id string) {
+		for _, i := range children[id] {
+			if seen[todos[i].ID] {
+				continue
+			}
+			seen[todos[i].ID] = true
+			if todos[i].Done {
+				done++
+			}
+			total++
+			walk(todos[i].ID)
+		}
+	}
+	walk(id)
+	return done, total
+}
+
+// reparent returns t moved under parent.
+func reparent(t Todo, parent string) Todo {
+	t.ParentID = parent
+	t.UpdatedAt = time.Now()
+	return t
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBuildTree(t *testing.T) {
+	tests := []struct {
+		name       string
+		todos      []Todo
+		collapsed  map[string]bool
+		wantRows   []int
+		wantDepths []int
+	}{
+		{
+			name:       "flat",
+			todos:      []Todo{{ID: "A"}, {ID: "B"}},
+			wantRows:   []int{0, 1},
+			wantDepths: []int{0, 0},
+		},
+		{
+			name:       "children follow their parent",
+			todos:      []Todo{{ID: "A"}, {ID: "B"}, {ID: "C", ParentID: "A"}, {ID: "D", ParentID: "C"}},
+			wantRows:   []int{0, 2, 3, 1},
+			wantDepths: []int{0, 1, 2, 0},
+		},
+		{
+			name:       "collapsed parent hides descendants",
+			todos:      []Todo{{ID: "A"}, {ID: "B", ParentID: "A"}, {ID: "C", ParentID: "B"}, {ID: "D"}},
+			collapsed:  map[string]bool{"A": true},
+			wantRows:   []int{0, 3},
+			wantDepths: []int{0, 0},
+		},
+		{
+			name:       "missing parent counts as top-level",
+			todos:      []Todo{{ID: "A", ParentID: "gone"}},
+			wantRows:   []int{0},
+			wantDepths: []int{0},
+		},
+		{
+			name:       "own parent",
+			todos:      []Todo{{ID: "A", ParentID: "A"}},
+			wantRows:   []int{0},
+			wantDepths: []int{0},
+		},
+		{
+			name:       "two-todo cycle",
+			todos:      []Todo{{ID: "A", ParentID: "B"}, {ID: "B", ParentID: "A"}, {ID: "C"}},
+			wantRows:   []int{2, 0, 1},
+			wantDepths: []int{0, 0, 1},
+		},
+		{
+			name:       "three-todo cycle",
+			todos:      []Todo{{ID: "A", ParentID: "C"}, {ID: "B", ParentID: "A"}, {ID: "C", ParentID: "B"}},
+			wantRows:   []int{0, 1, 2},
+			wantDepths: []int{0, 1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, depths := buildTree(tt.todos, sortNone, false, tt.collapsed)
+			if !slices.Equal(rows, tt.wantRows) || !slices.Equal(depths, tt.wantDepths) {
+				t.Errorf("buildTree = %v, %v; want %v, %v", rows, depths, tt.wantRows, tt.wantDepths)
+			}
+		})
+	}
+}
+
+func TestProgress(t *testing.T) {
+	tests := []struct {
+		name      string
+		todos     []Todo
+		id        string
+		wantDone  int
+		wantTotal int
+	}{
+		{
+			name:  "no children",
+			todos: []Todo{{ID: "A"}},
+			id:    "A",
+		},
+		{
+			name:      "counts grandchildren",
+			todos:     []Todo{{ID: "A"}, {ID: "B", ParentID: "A", Done: true}, {ID: "C", ParentID: "B"}, {ID: "D", ParentID: "B", Done: true}},
+			id:        "A",
+			wantDone:  2,
+			wantTotal: 3,
+		},
+		{
+			name:      "two-todo cycle",
+			todos:     []Todo{{ID: "A", ParentID: "B"}, {ID: "B", ParentID: "A", Done: true}},
+			id:        "A",
+			wantDone:  1,
+			wantTotal: 1,
+		},
+		{
+			name:      "cycle below the todo",
+			todos:     []Todo{{ID: "A"}, {ID: "B", ParentID: "A"}, {ID: "C", ParentID: "D"}, {ID: "D", ParentID: "C"}, {ID: "E", ParentID: "B"}},
+			id:        "A",
+			wantTotal: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done, total := progress(tt.todos, childIndex(tt.todos), tt.id)
+			if done != tt.wantDone || total != tt.wantTotal {
+				t.Errorf("progress = %d/%d, want %d/%d", done, total, tt.wantDone, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestSubtree(t *testing.T) {
+	todos := []Todo{{ID: "C", ParentID: "B"}, {ID: "A"}, {ID: "B", ParentID: "A"}, {ID: "D"}, {ID: "E", ParentID: "E"}}
+	if got, want := subtree(todos, "A"), []int{0, 1, 2}; !slices.Equal(got, want) {
+		t.Errorf("subtree(A) = %v, want %v", got, want)
+	}
+	if got, want := subtree(todos, "E"), []int{4}; !slices.Equal(got, want) {
+		t.Errorf("subtree(E) = %v, want %v", got, want)
+	}
+}
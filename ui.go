@@ -2,38 +2,62 @@ package main
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
-func (m model) View() string {
-	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FAFAFA")).Background(lipgloss.Color("#7D56F4")).Padding(0, 1)
-	cursorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF7CCB"))
-	statusStyle := lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("#888"))
-	doneStyle := lipgloss.NewStyle().Faint(true).Strikethrough(true)
-	urStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF3333")).Bold(true)
-	medStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFD700")).Bold(true)
-	lowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#00CC44")).Bold(true)
-	overdueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Bold(true).Underline(true)
+var (
+	headerStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FAFAFA")).Background(lipgloss.Color("#7D56F4")).Padding(0, 1)
+	cursorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF7CCB"))
+	statusStyle  = lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("#888"))
+	doneStyle    = lipgloss.NewStyle().Faint(true).Strikethrough(true)
+	urStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF3333")).Bold(true)
+	medStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFD700")).Bold(true)
+	lowStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#00CC44")).Bold(true)
+	overdueStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Bold(true).Underline(true)
+	matchStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#000000")).Background(lipgloss.Color("#FFD700"))
+)
 
+func (m model) View() string {
 	if m.mode == modeHelp {
 		var b strings.Builder
 		b.WriteString(headerStyle.Render(" Go-Do-It — Help Menu ") + "\n\n")
 		b.WriteString("Keybindings:\n\n")
 		b.WriteString("  j / ↓         Move cursor down\n")
 		b.WriteString("  k / ↑         Move cursor up\n")
+		b.WriteString("  pgdown / pgup Page down / up\n")
+		b.WriteString("  ctrl+d/ctrl+u Half a page down / up\n")
+		b.WriteString("  g / G         Go to the first / last todo\n")
 		b.WriteString("  a             Add a new todo\n")
 		b.WriteString("  d             Delete selected todo\n")
-		b.WriteString("  u             Undo last todo deletion\n")
+		b.WriteString("  u             Undo the last change\n")
+		b.WriteString("  ctrl+r        Redo the last undone change\n")
 		b.WriteString("  D             Delete all todos\n")
 		b.WriteString("  e             Edit selected todo\n")
-		b.WriteString("  <space>       Toggle completion\n")
+		b.WriteString("  enter         Show the whole todo, with its notes\n")
+		b.WriteString("  v             Show or hide the detail pane\n")
+		b.WriteString("  E             Edit the notes of selected todo in $EDITOR\n")
+		b.WriteString("  A             Add a subtask to selected todo\n")
+		b.WriteString("  > / <         Make a subtask of the todo above / move up a level\n")
+		b.WriteString("  c / ← / →     Collapse or expand subtasks\n")
+		b.WriteString("  <space>       Toggle completion (a repeating todo adds its next one)\n")
 		b.WriteString("  r             Reload todos from file\n")
+		b.WriteString("  R             Retry a failed load or save\n")
 		b.WriteString("  h             Show this help menu\n")
 		b.WriteString("  q             Quit the application\n")
-		b.WriteString("  t             Tag search\n")
+		b.WriteString("  t             Tag search (filter todos by tags)\n")
+		b.WriteString("  s             Cycle sort (none, priority, due, created, alpha, tag, done)\n")
+		b.WriteString("  S             Reverse sort order\n")
+		b.WriteString("  /             Search todo text, tags and notes\n")
+		b.WriteString("  n / N         Jump to next / previous match\n")
+		b.WriteString("  esc           Clear the search, then the tag filter\n")
+		b.WriteString("  L             Switch to another list\n")
+		b.WriteString("  m             Move selected todo to another list\n")
 		b.WriteString("  esc/any key   Return to todo list\n")
 		b.WriteString("\n")
 		b.WriteString(statusStyle.Render("Press any key or 'esc' to return to your todos."))
@@ -44,24 +68,14 @@ func (m model) View() string {
 	if m.mode == modeTagSearch {
 		var b strings.Builder
 		b.WriteString(headerStyle.Render(" Tag Search ") + "\n\n")
-		b.WriteString("Type to search tags. Press esc to return.\n\n")
+		mode := "AND (todos with all tags)"
+		if m.tagSearchAny {
+			mode = "OR (todos with any tag)"
+		}
+		b.WriteString("Match: " + mode + "\n\n")
 		b.WriteString(m.tagSearchInput.View() + "\n\n")
 
-		// Collect all tags
-		tagSet := make(map[string]struct{})
-		for _, t := range m.todos {
-			for _, tag := range t.Tags {
-				tagSet[tag] = struct{}{}
-			}
-		}
-		// Filter tags by input
-		var tags []string
-		input := strings.ToLower(m.tagSearchInput.Value())
-		for tag := range tagSet {
-			if input == "" || strings.Contains(strings.ToLower(tag), input) {
-				tags = append(tags, tag)
-			}
-		}
+		tags := tagSuggestions(m.todos, m.tagSearchInput.Value())
 		if len(tags) == 0 {
 			b.WriteString("No tags found.\n")
 		} else {
@@ -70,63 +84,116 @@ func (m model) View() string {
 				b.WriteString("  - " + tag + "\n")
 			}
 		}
+		if f := parseTagFilter(m.tagSearchInput.Value(), m.tagSearchAny); f.active() {
+			n := 0
+			for _, t := range m.todos {
+				if f.matches(t) {
+					n++
+				}
+			}
+			b.WriteString(fmt.Sprintf("\n%d todo(s) match %s\n", n, f))
+		}
 		b.WriteString("\n")
 		b.WriteString(statusStyle.Render(m.status))
 		b.WriteString("\n\n")
-		b.WriteString("Controls: esc:back\n")
+		b.WriteString("Controls: enter:filter tab:complete ctrl+o:and/or esc:back\n")
 		return b.String()
 	}
 
-	numCol := 4
-	taskCol := 30
-	dueCol := 12
-	prioCol := 10
-	tagsCol := 18
+	if m.mode == modeLists {
+		var b strings.Builder
+		b.WriteString(headerStyle.Render(" Lists ") + "\n\n")
+		for i, name := range m.listNames {
+			prefix := "  "
+			if i == m.listCursor {
+				prefix = cursorStyle.Render("> ")
+			}
+			label := name
+			if name == m.listName {
+				label += " (current)"
+			}
+			b.WriteString(prefix + label + "\n")
+		}
+		b.WriteString("\n")
+		if m.listNew {
+			b.WriteString("New list name:\n")
+			b.WriteString(m.textInput.View() + "\n\n")
+		}
+		b.WriteString(statusStyle.Render(m.status))
+		b.WriteString("\n\n")
+		b.WriteString("Controls: j/down k/up enter:select n:new-list esc:back\n")
+		return b.String()
+	}
 
-	space := " "
-	sep := space
+	if m.mode == modeDetail {
+		if i, ok := m.selected(); ok {
+			var b strings.Builder
+			b.WriteString(headerStyle.Render(" Todo ") + "\n\n")
+			lines := m.detailLines(m.todos[i], max(m.width, 40))
+			// Keep the status and controls on screen when the notes are long.
+			if room := m.detailRoom(); m.height > 0 && len(lines) > room {
+				m.detailScroll = min(m.detailScroll, len(lines)-room)
+				lines = lines[m.detailScroll : m.detailScroll+room]
+			}
+			b.WriteString(strings.Join(lines, "\n") + "\n\n")
+			b.WriteString(statusStyle.Render(m.status))
+			b.WriteString("\n\n")
+			b.WriteString("Controls: E:edit-notes e:edit <space>:toggle j/k:scroll esc:back\n")
+			return b.String()
+		}
+	}
 
 	var b strings.Builder
-	b.WriteString(headerStyle.Render(" Go-Do-It — Bubble Tea TUI ") + "\n\n")
-
+	b.WriteString(m.listTop())
 	if len(m.todos) == 0 {
 		b.WriteString("No todos yet — press 'a' to add one.\n\n")
+	} else if len(m.rows) == 0 {
+		b.WriteString("No todos match.\n\n")
 	} else {
-		headerLine := fmt.Sprintf("%-*s%s%-*s%s%-*s%s%-*s%s%-*s",
-			numCol, "#", sep,
-			taskCol, "Todo", sep,
-			dueCol, "Due Date", sep,
-			prioCol, "Priority", sep,
-			tagsCol, "Tags",
-		)
-		b.WriteString(headerLine + "\n")
-		b.WriteString(strings.Repeat("-", len(headerLine)) + "\n")
-
-		for i, t := range m.todos {
+		var tb strings.Builder
+		children := childIndex(m.todos)
+		taskWidth, tagsWidth := 0, 0
+		for r, i := range m.rows {
+			branch, count, notesMark := m.rowMarkers(r, children)
+			taskWidth = max(taskWidth, lipgloss.Width(branch+m.todos[i].Text+count+notesMark))
+			tagsWidth = max(tagsWidth, lipgloss.Width(strings.Join(m.todos[i].Tags, ", ")))
+		}
+		layout := newTableLayout(m.tableWidth(), len(m.rows), taskWidth, tagsWidth)
+		tb.WriteString(layout.row("  ", "#", "Todo", "Due Date", "Priority", "Tags") + "\n")
+		tb.WriteString("  " + strings.Repeat("-", layout.width()-cursorWidth) + "\n")
+
+		rows := m.listRows()
+		off := m.visibleOffset(rows)
+		end := min(off+rows, len(m.rows))
+		for i := off; i < end; i++ {
+			t := m.todos[m.rows[i]]
 			rowPrefix := "  "
 			if i == m.cursor && m.mode == modeView {
 				rowPrefix = cursorStyle.Render("> ")
 			}
-			task := t.Text
-			if len([]rune(task)) > taskCol {
-				r := []rune(task)
-				if len(r) > taskCol-3 {
-					r = r[:taskCol-3]
-				}
-				task = string(r) + "..."
-			}
+			branch, count, notesMark := m.rowMarkers(i, children)
+			// The text is cut short rather than the markers after it.
+			room := max(layout.task-lipgloss.Width(branch+count+notesMark), 4)
 			isOverdue := false
 			if t.DueDate != "" && !t.Done {
-				if due, err := time.Parse("2006-01-02", t.DueDate); err == nil {
+				if due, err := time.Parse(dateLayout, t.DueDate); err == nil {
 					if due.Before(time.Now()) {
 						isOverdue = true
 					}
 				}
 			}
+			hit, _ := searchTodo(t, m.searchPattern())
+			taskStyle := lipgloss.NewStyle()
 			if t.Done {
-				task = doneStyle.Render(task)
+				taskStyle = doneStyle
 			} else if isOverdue {
-				task = overdueStyle.Render(task)
+				taskStyle = overdueStyle
+			}
+			task := branch + highlight(t.Text, room, hit.text, taskStyle, matchStyle) + statusStyle.Render(count)
+			if hit.notes {
+				task += matchStyle.Render(notesMark)
+			} else {
+				task += statusStyle.Render(notesMark)
 			}
 			var prioLabel string
 			switch t.Priority {
@@ -143,20 +210,213 @@ func (m model) View() string {
 			if isOverdue && !t.Done && t.DueDate != "" {
 				dueLabel = overdueStyle.Render(t.DueDate)
 			}
-			tagsLabel := strings.Join(t.Tags, ", ")
-			row := fmt.Sprintf("%s%-*d%s%-*s%s%-*s%s%-*s%s%-*s",
-				rowPrefix,
-				numCol, i+1, sep,
-				taskCol, task, sep,
-				dueCol, dueLabel, sep,
-				prioCol, prioLabel, sep,
-				tagsCol, tagsLabel,
-			)
-			b.WriteString(row + "\n")
+			// Tags are highlighted as the one string the column shows.
+			var tagsText string
+			var tagsHit []int
+			for j, tag := range t.Tags {
+				if j > 0 {
+					tagsText += ", "
+				}
+				if hit.tags != nil {
+					for _, p := range hit.tags[j] {
+						tagsHit = append(tagsHit, utf8.RuneCountInString(tagsText)+p)
+					}
+				}
+				tagsText += tag
+			}
+			tagsLabel := highlight(tagsText, layout.tags, tagsHit, lipgloss.NewStyle(), matchStyle)
+			row := layout.row(rowPrefix, strconv.Itoa(i+1), task, dueLabel, prioLabel, tagsLabel)
+			tb.WriteString(row + "\n")
 		}
-		b.WriteString("\n")
+		if len(m.rows) > rows {
+			tb.WriteString(statusStyle.Render(fmt.Sprintf("  %d–%d of %d", off+1, end, len(m.rows))) + "\n")
+		}
+		b.WriteString(m.withDetailPane(strings.TrimSuffix(tb.String(), "\n")) + "\n\n")
+	}
+
+	b.WriteString(m.listFooter())
+	return b.String()
+}
+
+// detailRoom is how many lines of the detail view fit above its status and
+// controls, and at least one however short the terminal is.
+func (m *model) detailRoom() int {
+	return max(m.height-6, 1)
+}
+
+// detailLines renders every field of t, wrapped to width, for the detail
+// view.
+func (m *model) detailLines(t Todo, width int) []string {
+	labelStyle := lipgloss.NewStyle().Bold(true).Width(11)
+	faint := lipgloss.NewStyle().Faint(true)
+	wrap := lipgloss.NewStyle().Width(max(width-11, 20))
+	var lines []string
+	field := func(label, value string) {
+		if value == "" {
+			return
+		}
+		for n, line := range strings.Split(wrap.Render(value), "\n") {
+			if n == 0 {
+				lines = append(lines, labelStyle.Render(label)+line)
+			} else {
+				lines = append(lines, strings.Repeat(" ", 11)+line)
+			}
+		}
+	}
+	stamp := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Local().Format("2006-01-02 15:04")
 	}
 
+	field("Todo", t.Text)
+	status := "open"
+	if t.Done {
+		status = "done"
+	}
+	field("Status", status)
+	field("Priority", t.Priority)
+	field("Due", t.DueDate)
+	field("Tags", strings.Join(t.Tags, ", "))
+	if r, err := parseRecurrence(t.Recurrence); t.Recurrence != "" && err == nil {
+		field("Repeats", r.describe())
+	}
+	if p := indexOfTodo(m.todos, t.ParentID); p >= 0 {
+		field("Subtask of", m.todos[p].Text)
+	}
+	children := childIndex(m.todos)
+	if len(children[t.ID]) > 0 {
+		done, total := progress(m.todos, children, t.ID)
+		field("Subtasks", fmt.Sprintf("%d of %d done", done, total))
+	}
+	field("Created", stamp(t.CreatedAt))
+	field("Updated", stamp(t.UpdatedAt))
+	field("Completed", stamp(t.CompletedAt))
+	field("ID", faint.Render(t.ID))
+
+	if changes := m.history.changesTo(t.ID); len(changes) > 0 {
+		lines = append(lines, "", labelStyle.Render("History")+faint.Render("this session"))
+		for _, c := range changes {
+			lines = append(lines, faint.Render(stamp(c.at))+"  "+c.what)
+		}
+	}
+
+	lines = append(lines, "")
+	if t.Notes == "" {
+		return append(lines, faint.Render("No notes. Press E to write some."))
+	}
+	lines = append(lines, labelStyle.Render("Notes"))
+	return append(lines, strings.Split(lipgloss.NewStyle().Width(width).Render(t.Notes), "\n")...)
+}
+
+// detailPaneWidth is the narrowest the detail pane goes beside the table;
+// on a narrower terminal it goes below the table instead.
+const detailPaneWidth = 36
+
+// withDetailPane adds the detail pane for the todo under the cursor to the
+// rendered table, if it is turned on.
+func (m *model) withDetailPane(table string) string {
+	i, ok := m.selected()
+	if !m.showDetail || !ok {
+		return table
+	}
+	paneStyle := lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("#7D56F4")).Padding(0, 1)
+	// The border takes two columns and the padding two more.
+	if !m.paneBelow() {
+		side := m.termWidth() - lipgloss.Width(table) - 1
+		lines := clipLines(m.detailLines(m.todos[i], side-4), max(lipgloss.Height(table), 5))
+		return lipgloss.JoinHorizontal(lipgloss.Top, table, " ", paneStyle.Width(side-2).Render(strings.Join(lines, "\n")))
+	}
+	width := m.termWidth()
+	lines := m.detailLines(m.todos[i], width-4)
+	if m.height > 0 {
+		lines = clipLines(lines, m.paneBelowHeight()-2)
+	}
+	return table + "\n" + paneStyle.Width(width-2).Render(strings.Join(lines, "\n"))
+}
+
+// paneBelow reports whether the detail pane is shown below the table,
+// because the terminal is too narrow to have it alongside.
+func (m *model) paneBelow() bool {
+	_, ok := m.selected()
+	return m.showDetail && ok && m.tableWidth() == m.termWidth()
+}
+
+// paneBelowHeight is how many lines the detail pane takes below the table,
+// borders included: at most half the screen.
+func (m *model) paneBelowHeight() int {
+	i, _ := m.selected()
+	return min(len(m.detailLines(m.todos[i], m.termWidth()-4)), max(m.height/2-2, 5)) + 2
+}
+
+// clipLines cuts lines down to n, saying so on the last one.
+func clipLines(lines []string, n int) []string {
+	if len(lines) <= n {
+		return lines
+	}
+	return append(lines[:n-1:n-1], lipgloss.NewStyle().Faint(true).Render("… enter shows the rest"))
+}
+
+// rowMarkers returns what goes around the text of the todo on row r.
+// Subtasks are indented under their parent; parents get an expand marker
+// and a done/total count of their subtasks. Repeating todos are marked ↻
+// and todos with notes ✎.
+func (m *model) rowMarkers(r int, children map[string][]int) (branch, count, notes string) {
+	t := m.todos[m.rows[r]]
+	branch = strings.Repeat("  ", m.depths[r])
+	if len(children[t.ID]) > 0 {
+		if m.collapsed[t.ID] {
+			branch += "▸ "
+		} else {
+			branch += "▾ "
+		}
+		done, total := progress(m.todos, children, t.ID)
+		count = fmt.Sprintf(" %d/%d", done, total)
+	}
+	if t.Recurrence != "" {
+		count += " ↻"
+	}
+	if t.Notes != "" {
+		notes = " ✎"
+	}
+	return branch, count, notes
+}
+
+// listTop renders what goes above the todo table: the title, and the
+// filter and search in effect.
+func (m *model) listTop() string {
+	var b strings.Builder
+	listStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#7D56F4"))
+	header := headerStyle.Render(" Go-Do-It — Bubble Tea TUI ") + " " + listStyle.Render("List: "+m.listName)
+	if m.sortKey != sortNone || m.sortDesc {
+		arrow := "↑"
+		if m.sortDesc {
+			arrow = "↓"
+		}
+		header += " " + statusStyle.Render("Sort: "+m.sortKey.String()+" "+arrow)
+	}
+	b.WriteString(ansi.Truncate(header, m.termWidth(), "…") + "\n\n")
+
+	if m.filter.active() {
+		filterStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF7CCB"))
+		b.WriteString(filterStyle.Render(fmt.Sprintf("Filter: %s (%d of %d) — esc to clear", m.filter, len(m.rows), len(m.todos))) + "\n\n")
+	}
+
+	if m.mode == modeSearch {
+		b.WriteString(m.searchInput.View() + "\n\n")
+	} else if m.search != "" {
+		searchStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF7CCB"))
+		b.WriteString(searchStyle.Render(fmt.Sprintf("Search: %q (%d matches) — n/N to jump, esc to clear", m.search, m.countMatches())) + "\n\n")
+	}
+
+	return b.String()
+}
+
+// listFooter renders what goes below the todo table: the prompt of the
+// current mode, the status line and the controls.
+func (m *model) listFooter() string {
+	var b strings.Builder
 	switch m.mode {
 	case modeAdd:
 		if m.dueDateSelect {
@@ -177,9 +437,18 @@ func (m model) View() string {
 		} else if m.tagsSelect {
 			b.WriteString("Add mode — enter tags (comma separated) or leave blank and press Enter\n")
 			b.WriteString(m.textInput.View() + "\n")
+		} else if m.recurSelect {
+			b.WriteString("Add mode — how often it repeats (e.g. every monday, every 2 weeks, FREQ=MONTHLY;BYMONTHDAY=1) or leave blank\n")
+			b.WriteString(m.textInput.View() + "\n")
+			if r, err := parseRecurrence(m.textInput.Value()); err == nil {
+				b.WriteString(statusStyle.Render("Repeats "+r.describe()) + "\n")
+			}
 		} else {
-			b.WriteString("Add mode — press Enter to continue, Esc to cancel\n")
+			b.WriteString("Add mode — Enter to add, Tab to fill in details step by step, Esc to cancel\n")
 			b.WriteString(m.textInput.View() + "\n")
+			if p := parseQuickAdd(m.textInput.Value(), time.Now()).preview(); p != "" {
+				b.WriteString(statusStyle.Render("→ "+p) + "\n")
+			}
 		}
 	case modeEdit:
 		if m.dueDateSelect {
@@ -200,6 +469,12 @@ func (m model) View() string {
 		} else if m.tagsSelect {
 			b.WriteString("Edit mode — enter tags (comma separated) or leave blank and press Enter\n")
 			b.WriteString(m.textInput.View() + "\n")
+		} else if m.recurSelect {
+			b.WriteString("Edit mode — how often it repeats (e.g. every monday, every 2 weeks, FREQ=MONTHLY;BYMONTHDAY=1) or leave blank\n")
+			b.WriteString(m.textInput.View() + "\n")
+			if r, err := parseRecurrence(m.textInput.Value()); err == nil {
+				b.WriteString(statusStyle.Render("Repeats "+r.describe()) + "\n")
+			}
 		} else {
 			b.WriteString("Edit mode — press Enter to continue, Esc to cancel\n")
 			b.WriteString(m.textInput.View() + "\n")
@@ -211,9 +486,9 @@ func (m model) View() string {
 	}
 
 	b.WriteString("\n")
-	b.WriteString(statusStyle.Render(m.status))
+	b.WriteString(m.wrapWidth(statusStyle.Render(m.status)))
 	b.WriteString("\n\n")
-	b.WriteString("Controls: j/down k/up a:add d:delete D:delete-all e:edit <space>:toggle r:reload u:undo h:help t:tag-search q:quit\n")
+	b.WriteString(m.wrapWidth(strings.TrimSuffix("Controls: j/down k/up pgdn/pgup g/G a:add A:subtask d:delete D:delete-all e:edit E:notes enter:details v:pane >/<:indent c:collapse <space>:toggle r:reload u:undo ctrl+r:redo h:help t:tag-search s/S:sort /:search n/N:next/prev L:lists m:move q:quit\n", "\n")) + "\n")
 
 	return b.String()
 }
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"fmt"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Start from the rows the last View showed.
+	m.offset = m.visibleOffset(m.listRows())
+
 	switch msg := msg.(type) {
 
 	case tea.KeyMsg:
@@ -15,107 +20,337 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch m.mode {
 
 		case modeView:
+			quitArmed := m.quitArmed
+			m.quitArmed = false
 			switch k {
 			case "t":
 				m.mode = modeTagSearch
-				m.tagSearchInput.SetValue("")
+				m.tagSearchInput.SetValue(strings.Join(m.filter.tags, " "))
+				m.tagSearchInput.CursorEnd()
 				m.tagSearchInput.Focus()
-				m.status = "Tag search: type to filter tags. Press esc to return."
+				m.tagSearchAny = m.filter.any
+				m.status = "Tag search: type tags, tab to complete, ctrl+o for AND/OR, Enter to filter."
+				return m, nil
+			case "esc":
+				if m.search != "" {
+					m.search = ""
+					m.status = "Search cleared."
+				} else if m.filter.active() {
+					m.filter = tagFilter{}
+					m.refresh()
+					m.status = "Filter cleared."
+				}
+			case "/":
+				m.mode = modeSearch
+				m.searchInput.SetValue("")
+				m.searchInput.Focus()
+				m.status = "Search: type to filter, Enter to keep highlights, esc to cancel."
 				return m, nil
+			case "s":
+				m.sortKey = m.sortKey.next()
+				m.refresh()
+				m.status = "Sorted by " + m.sortKey.String() + "."
+				m.savePrefs()
+			case "S":
+				m.sortDesc = !m.sortDesc
+				m.refresh()
+				m.status = "Sort order reversed."
+				m.savePrefs()
+			case "n":
+				m.jumpMatch(1)
+			case "N":
+				m.jumpMatch(-1)
 			case "j", "down":
-				if m.cursor < len(m.todos)-1 {
+				if m.cursor < len(m.rows)-1 {
 					m.cursor++
 				}
 			case "k", "up":
 				if m.cursor > 0 {
 					m.cursor--
 				}
+			case "pgdown":
+				m.scroll(m.listRows())
+			case "pgup":
+				m.scroll(-m.listRows())
+			case "ctrl+d":
+				m.scroll(max(m.listRows()/2, 1))
+			case "ctrl+u":
+				m.scroll(-max(m.listRows()/2, 1))
+			case "g", "home":
+				m.cursor = 0
+			case "G", "end":
+				m.cursor = max(len(m.rows)-1, 0)
 			case "a":
 				m.mode = modeAdd
+				m.addParent = ""
 				m.textInput.SetValue("")
 				m.textInput.Focus()
-				m.status = "Add a new todo. Type and press Enter."
+				m.status = "Add a todo, e.g. \"Pay rent tomorrow !urgent #home\"."
+			case "A":
+				if i, ok := m.selected(); ok {
+					m.mode = modeAdd
+					m.addParent = m.todos[i].ID
+					m.textInput.SetValue("")
+					m.textInput.Focus()
+					m.status = fmt.Sprintf("Add a subtask to %s.", quoteText(m.todos[i].Text))
+				}
+			case "c":
+				if i, ok := m.selected(); ok && len(childIndex(m.todos)[m.todos[i].ID]) > 0 {
+					id := m.todos[i].ID
+					m.collapsed[id] = !m.collapsed[id]
+					m.refresh()
+				}
+			case "left":
+				if i, ok := m.selected(); ok {
+					id := m.todos[i].ID
+					if len(childIndex(m.todos)[id]) > 0 && !m.collapsed[id] {
+						m.collapsed[id] = true
+						m.refresh()
+					} else if p := m.todos[i].ParentID; p != "" {
+						m.focus(p)
+					}
+				}
+			case "right":
+				if i, ok := m.selected(); ok && m.collapsed[m.todos[i].ID] {
+					delete(m.collapsed, m.todos[i].ID)
+					m.refresh()
+				}
+			case ">":
+				m.indent()
+			case "<":
+				m.outdent()
 			case "d":
-				if len(m.todos) > 0 {
+				if i, ok := m.selected(); ok {
 					m.mode = modeConfirmDelete
-					m.confirmIdx = m.cursor
+					m.confirmIdx = i
 					m.status = "Delete this todo? (y/n)"
+					if n := len(subtree(m.todos, m.todos[i].ID)) - 1; n > 0 {
+						m.status = fmt.Sprintf("Delete this todo and its %d subtask(s)? (y/n)", n)
+					}
 				}
 			case "D":
-				if len(m.todos) > 0 {
+				if len(m.rows) > 0 {
 					m.mode = modeConfirmDeleteAll
 					m.status = "Delete ALL todos? (y/n)"
+					if m.filter.active() {
+						m.status = fmt.Sprintf("Delete all %d todos matching %s? (y/n)", len(m.rows), m.filter)
+					}
 				}
 			case "e":
-				if len(m.todos) > 0 {
+				if i, ok := m.selected(); ok {
 					m.mode = modeEdit
-					m.editIdx = m.cursor
+					m.editIdx = i
 
-					currentTodo := m.todos[m.cursor].Text
+					currentTodo := m.todos[i].Text
 					m.textInput.SetValue(currentTodo)
 					m.textInput.Focus()
 					m.status = "Edit todo. Press Enter to continue."
 				}
 			case " ":
-				if len(m.todos) > 0 {
-					m.todos[m.cursor].Done = !m.todos[m.cursor].Done
-					saveTodos(m.todos)
-					m.status = "Toggled completion."
+				if i, ok := m.selected(); ok {
+					m.toggle(i)
+				}
+			case "enter":
+				if _, ok := m.selected(); ok {
+					m.mode = modeDetail
+					m.detailScroll = 0
+					m.status = "E to edit the notes, esc to go back."
+				}
+			case "E":
+				if i, ok := m.selected(); ok {
+					return m, m.editNotes(i)
 				}
+			case "v":
+				m.showDetail = !m.showDetail
 			case "r":
-				m.todos = loadTodos()
-				m.status = "Todos reloaded."
+				if m.pending == retrySave {
+					m.status = "Reload would discard unsaved changes. Press R to retry saving."
+				} else if m.load() {
+					m.status = "Todos reloaded."
+				}
+			case "R":
+				m.retry()
 			case "u":
-				if m.canUndo {
-					idx := m.lastDeletedIndex
-					if idx < 0 || idx > len(m.todos) {
-						idx = len(m.todos)
-					}
-					m.todos = append(m.todos[:idx], append([]Todo{m.lastDeletedTodo}, m.todos[idx:]...)...)
-					saveTodos(m.todos)
-					m.canUndo = false
-					m.status = "Undo successful."
+				m.undo()
+			case "ctrl+r":
+				m.redo()
+			case "L":
+				m.openListPicker(false)
+			case "m":
+				if _, ok := m.selected(); ok {
+					m.openListPicker(true)
 				}
 			case "h":
 				m.mode = modeHelp
 			case "q":
+				if m.pending == retrySave && !quitArmed {
+					m.quitArmed = true
+					m.status = "Unsaved changes will be lost. Press q again to quit or R to retry saving."
+					return m, nil
+				}
 				return m, tea.Quit
 			}
 
 		case modeTagSearch:
-			var cmd tea.Cmd
-			m.tagSearchInput, cmd = m.tagSearchInput.Update(msg)
-			if k == "esc" {
+			switch k {
+			case "esc":
 				m.mode = modeView
 				m.tagSearchInput.Blur()
 				m.status = "Returned from tag search."
-				return m, cmd
+				return m, nil
+			case "enter":
+				m.mode = modeView
+				m.tagSearchInput.Blur()
+				m.filter = parseTagFilter(m.tagSearchInput.Value(), m.tagSearchAny)
+				m.refresh()
+				if m.filter.active() {
+					m.status = fmt.Sprintf("Showing %d todo(s) tagged %s. Press esc to clear.", len(m.rows), m.filter)
+				} else {
+					m.status = "Filter cleared."
+				}
+				return m, nil
+			case "ctrl+o":
+				m.tagSearchAny = !m.tagSearchAny
+				return m, nil
+			case "tab":
+				if tags := tagSuggestions(m.todos, m.tagSearchInput.Value()); len(tags) > 0 {
+					val := m.tagSearchInput.Value()
+					if i := strings.LastIndexAny(val, " ,"); i >= 0 {
+						val = val[:i+1]
+					} else {
+						val = ""
+					}
+					m.tagSearchInput.SetValue(val + tags[0] + " ")
+					m.tagSearchInput.CursorEnd()
+				}
+				return m, nil
 			}
+			var cmd tea.Cmd
+			m.tagSearchInput, cmd = m.tagSearchInput.Update(msg)
 			return m, cmd
 
-		case modeAdd:
-			var cmd tea.Cmd = nil
+		case modeSearch:
+			switch k {
+			case "esc":
+				m.mode = modeView
+				m.searchInput.Blur()
+				m.refresh()
+				m.status = "Search cancelled."
+				return m, nil
+			case "enter":
+				m.mode = modeView
+				m.searchInput.Blur()
+				m.search = strings.TrimSpace(m.searchInput.Value())
+				m.refresh()
+				if m.search == "" {
+					m.status = "Search cleared."
+				} else if n := m.countMatches(); n == 0 {
+					m.status = fmt.Sprintf("Pattern not found: %s", m.search)
+				} else {
+					m.status = fmt.Sprintf("%d match(es) for %q. n/N to jump, esc to clear.", n, m.search)
+				}
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.searchInput, cmd = m.searchInput.Update(msg)
+			m.refresh()
+			return m, cmd
 
-			if !m.dueDateSelect && !m.prioritySelect && !m.tagsSelect {
+		case modeLists:
+			if m.listNew {
+				var cmd tea.Cmd
 				m.textInput, cmd = m.textInput.Update(msg)
-				if k == "enter" {
-					val := strings.TrimSpace(m.textInput.Value())
-					if val != "" {
-
-						m.tempTodoText = val
-						m.dueDateInput = ""
-						m.priorityInput = 1
-						m.tagsInput = ""
-						m.dueDateSelect = true
-						m.status = "Enter due date (YYYY-MM-DD) or leave blank and press Enter: "
-						m.textInput.SetValue("")
+				switch k {
+				case "enter":
+					name := strings.TrimSpace(m.textInput.Value())
+					m.listNew = false
+					m.textInput.Blur()
+					if err := validListName(name); err != nil {
+						m.status = fmt.Sprintf("Cannot create list: %v", err)
 						return m, cmd
+					}
+					m.mode = modeView
+					if m.listMove {
+						m.moveTodo(name)
 					} else {
+						m.switchList(name)
+					}
+				case "esc":
+					m.listNew = false
+					m.textInput.Blur()
+					m.status = "New list cancelled."
+				}
+				return m, cmd
+			}
+			switch k {
+			case "j", "down":
+				if m.listCursor < len(m.listNames)-1 {
+					m.listCursor++
+				}
+			case "k", "up":
+				if m.listCursor > 0 {
+					m.listCursor--
+				}
+			case "n":
+				m.listNew = true
+				m.textInput.SetValue("")
+				m.textInput.Focus()
+				m.status = "Name the new list and press Enter."
+			case "enter":
+				m.mode = modeView
+				name := m.listNames[m.listCursor]
+				if m.listMove {
+					m.moveTodo(name)
+				} else {
+					m.switchList(name)
+				}
+			case "esc", "q":
+				m.mode = modeView
+				m.status = "Returned from lists."
+			}
+
+		case modeAdd:
+			var cmd tea.Cmd = nil
+
+			if !m.dueDateSelect && !m.prioritySelect && !m.tagsSelect && !m.recurSelect {
+				m.textInput, cmd = m.textInput.Update(msg)
+				switch k {
+				case "enter":
+					// Enter adds the todo straight from the quick-add line.
+					q := parseQuickAdd(m.textInput.Value(), time.Now())
+					if q.text == "" {
 						m.status = "Empty todo not added."
 						m.mode = modeView
 						m.textInput.Blur()
 						return m, cmd
 					}
+					todo := newTodo(q.text)
+					todo.DueDate = q.due
+					todo.Priority = q.priority
+					todo.Tags = q.tags
+					todo.Recurrence = q.recurrence
+					m.addTodo(todo)
+					return m, cmd
+				case "tab":
+					// Tab goes through the fields one at a time, starting
+					// from whatever the quick-add line already set.
+					q := parseQuickAdd(m.textInput.Value(), time.Now())
+					if q.text == "" {
+						m.status = "Type the todo first, then press tab to fill in the details."
+						return m, cmd
+					}
+					m.tempTodoText = q.text
+					m.dueDateInput = q.due
+					m.priorityInput = priorityIndex(q.priority)
+					m.tagsInput = strings.Join(q.tags, ", ")
+					m.recurInput = ""
+					if r, err := parseRecurrence(q.recurrence); err == nil {
+						m.recurInput = r.describe()
+					}
+					m.dueDateSelect = true
+					m.status = "Enter due date (YYYY-MM-DD) or leave blank and press Enter: "
+					m.textInput.SetValue(m.dueDateInput)
+					return m, cmd
 				}
 				if k == "esc" {
 					m.mode = modeView
@@ -160,7 +395,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.prioritySelect = false
 					m.tagsSelect = true
 					m.status = "Enter tags (comma separated) or leave blank and press Enter: "
-					m.textInput.SetValue("")
+					m.textInput.SetValue(m.tagsInput)
 					return m, nil
 				case "esc":
 					m.prioritySelect = false
@@ -176,6 +411,30 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.textInput, cmd = m.textInput.Update(msg)
 				if k == "enter" {
 					m.tagsInput = strings.TrimSpace(m.textInput.Value())
+					m.tagsSelect = false
+					m.recurSelect = true
+					m.status = "Repeat? e.g. \"every monday\", \"monthly on the 1st\" or an RRULE; leave blank for none: "
+					m.textInput.SetValue(m.recurInput)
+					return m, cmd
+				}
+				if k == "esc" {
+					m.tagsSelect = false
+					m.mode = modeView
+					m.status = "Add cancelled."
+					m.textInput.Blur()
+					return m, cmd
+				}
+				return m, cmd
+			}
+
+			if m.recurSelect {
+				m.textInput, cmd = m.textInput.Update(msg)
+				if k == "enter" {
+					rule, err := recurrenceInput(m.textInput.Value())
+					if err != nil {
+						m.status = fmt.Sprintf("%v. Try again or leave blank: ", err)
+						return m, cmd
+					}
 					priority := "medium"
 					if m.priorityInput == 0 {
 						priority = "urgent"
@@ -189,22 +448,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							tags[i] = strings.TrimSpace(tags[i])
 						}
 					}
-					m.todos = append(m.todos, Todo{
-						Text:     m.tempTodoText,
-						DueDate:  m.dueDateInput,
-						Priority: priority,
-						Tags:     tags,
-						Done:     false,
-					})
-					saveTodos(m.todos)
-					m.status = "Todo added!"
-					m.mode = modeView
-					m.tagsSelect = false
-					m.textInput.Blur()
+					todo := newTodo(m.tempTodoText)
+					todo.DueDate = m.dueDateInput
+					todo.Priority = priority
+					todo.Tags = tags
+					todo.Recurrence = rule
+					m.recurSelect = false
+					m.addTodo(todo)
 					return m, cmd
 				}
 				if k == "esc" {
-					m.tagsSelect = false
+					m.recurSelect = false
 					m.mode = modeView
 					m.status = "Add cancelled."
 					m.textInput.Blur()
@@ -216,7 +470,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case modeEdit:
 			var cmd tea.Cmd
 
-			if !m.dueDateSelect && !m.prioritySelect && !m.tagsSelect {
+			if !m.dueDateSelect && !m.prioritySelect && !m.tagsSelect && !m.recurSelect {
 				m.textInput, cmd = m.textInput.Update(msg)
 				if k == "enter" {
 					val := strings.TrimSpace(m.textInput.Value())
@@ -226,15 +480,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 						m.dueDateInput = m.todos[m.editIdx].DueDate
 
-						switch m.todos[m.editIdx].Priority {
-						case "urgent":
-							m.priorityInput = 0
-						case "low":
-							m.priorityInput = 2
-						default:
-							m.priorityInput = 1
-						}
+						m.priorityInput = priorityIndex(m.todos[m.editIdx].Priority)
 						m.tagsInput = strings.Join(m.todos[m.editIdx].Tags, ", ")
+						m.recurInput = ""
+						if r, err := parseRecurrence(m.todos[m.editIdx].Recurrence); err == nil {
+							m.recurInput = r.describe()
+						}
 						m.dueDateSelect = true
 						m.status = "Enter due date (YYYY-MM-DD) or leave blank and press Enter: "
 						m.textInput.SetValue(m.dueDateInput)
@@ -304,6 +555,30 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.textInput, cmd = m.textInput.Update(msg)
 				if k == "enter" {
 					m.tagsInput = strings.TrimSpace(m.textInput.Value())
+					m.tagsSelect = false
+					m.recurSelect = true
+					m.status = "Repeat? e.g. \"every monday\", \"monthly on the 1st\" or an RRULE; leave blank for none: "
+					m.textInput.SetValue(m.recurInput)
+					return m, cmd
+				}
+				if k == "esc" {
+					m.tagsSelect = false
+					m.mode = modeView
+					m.status = "Edit cancelled"
+					m.textInput.Blur()
+					return m, cmd
+				}
+				return m, cmd
+			}
+
+			if m.recurSelect {
+				m.textInput, cmd = m.textInput.Update(msg)
+				if k == "enter" {
+					rule, err := recurrenceInput(m.textInput.Value())
+					if err != nil {
+						m.status = fmt.Sprintf("%v. Try again or leave blank: ", err)
+						return m, cmd
+					}
 
 					priority := "medium"
 					if m.priorityInput == 0 {
@@ -319,19 +594,22 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						}
 					}
 
-					m.todos[m.editIdx].Text = m.tempTodoText
-					m.todos[m.editIdx].DueDate = m.dueDateInput
-					m.todos[m.editIdx].Priority = priority
-					m.todos[m.editIdx].Tags = tags
-					saveTodos(m.todos)
+					t := m.todos[m.editIdx]
+					t.Text = m.tempTodoText
+					t.DueDate = m.dueDateInput
+					t.Priority = priority
+					t.Tags = tags
+					t.Recurrence = rule
+					t.UpdatedAt = time.Now()
 					m.status = "Todo edited!"
+					m.exec(updateCmd("edit", m.todos[m.editIdx], t))
 					m.mode = modeView
-					m.tagsSelect = false
+					m.recurSelect = false
 					m.textInput.Blur()
 					return m, cmd
 				}
 				if k == "esc" {
-					m.tagsSelect = false
+					m.recurSelect = false
 					m.mode = modeView
 					m.status = "Edit cancelled"
 					m.textInput.Blur()
@@ -344,15 +622,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			switch k {
 			case "y", "enter":
 				if m.confirmIdx >= 0 && m.confirmIdx < len(m.todos) {
-					m.lastDeletedTodo = m.todos[m.confirmIdx]
-					m.lastDeletedIndex = m.confirmIdx
-					m.canUndo = true
-					m.todos = append(m.todos[:m.confirmIdx], m.todos[m.confirmIdx+1:]...)
-					saveTodos(m.todos)
 					m.status = "Todo deleted (press 'u' to undo)"
-					if m.cursor >= len(m.todos) && m.cursor > 0 {
-						m.cursor--
-					}
+					m.exec(newDeleteCmd(m.todos, subtree(m.todos, m.todos[m.confirmIdx].ID)))
 				}
 				m.mode = modeView
 			case "n", "esc":
@@ -363,10 +634,26 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case modeConfirmDeleteAll:
 			switch k {
 			case "y", "enter":
-				m.todos = []Todo{}
-				saveTodos(m.todos)
-				m.canUndo = false
-				m.status = "All todos deleted"
+				// Subtasks go with their parents, matching or not.
+				doomed := make(map[int]bool)
+				for _, t := range m.todos {
+					if m.filter.matches(t) {
+						for _, j := range subtree(m.todos, t.ID) {
+							doomed[j] = true
+						}
+					}
+				}
+				var indexes []int
+				for i := range m.todos {
+					if doomed[i] {
+						indexes = append(indexes, i)
+					}
+				}
+				m.status = "All todos deleted (press 'u' to undo)"
+				if m.filter.active() {
+					m.status = fmt.Sprintf("Deleted %d todo(s) tagged %s (press 'u' to undo)", len(indexes), m.filter)
+				}
+				m.exec(newDeleteCmd(m.todos, indexes))
 				m.mode = modeView
 				m.cursor = 0
 			case "n", "esc":
@@ -377,8 +664,39 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case modeHelp:
 			m.mode = modeView
 			m.status = "Returned from help."
+
+		case modeDetail:
+			i, ok := m.selected()
+			if !ok {
+				m.mode = modeView
+				break
+			}
+			switch k {
+			case "esc", "enter", "q":
+				m.mode = modeView
+				m.status = ""
+			case "E":
+				return m, m.editNotes(i)
+			case "e":
+				m.mode = modeEdit
+				m.editIdx = i
+				m.textInput.SetValue(m.todos[i].Text)
+				m.textInput.Focus()
+				m.status = "Edit todo. Press Enter to continue."
+			case " ":
+				m.toggle(i)
+			case "j", "down":
+				if n := len(m.detailLines(m.todos[i], max(m.width, 40))); m.detailScroll < n-m.detailRoom() {
+					m.detailScroll++
+				}
+			case "k", "up":
+				m.detailScroll = max(m.detailScroll-1, 0)
+			}
 		}
 
+	case notesEditedMsg:
+		m.notesEdited(msg)
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -387,3 +705,168 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// openListPicker shows the named lists, either to switch to one or, with
+// move set, to move the selected todo into one.
+func (m *model) openListPicker(move bool) {
+	if m.lists == nil {
+		m.status = "Only one list is available."
+		return
+	}
+	names, err := m.lists.names()
+	if err != nil {
+		m.status = fmt.Sprintf("Could not read lists: %v", err)
+		return
+	}
+	m.listNames = names
+	m.listCursor = 0
+	for i, n := range names {
+		if n == m.listName {
+			m.listCursor = i
+		}
+	}
+	m.listMove = move
+	m.listNew = false
+	m.mode = modeLists
+	if move {
+		m.status = "Move todo to which list? Enter to move, n for a new list, esc to cancel."
+	} else {
+		m.status = "Pick a list. Enter to switch, n for a new list, esc to cancel."
+	}
+}
+
+// switchList closes the current list and opens the named one.
+func (m *model) switchList(name string) {
+	if name == m.listName {
+		m.status = fmt.Sprintf("Already on list %q.", name)
+		return
+	}
+	if m.pending == retrySave {
+		m.status = "This list has unsaved changes. Press R to retry saving first."
+		return
+	}
+	store, err := m.lists.open(name)
+	if err != nil {
+		m.status = fmt.Sprintf("Could not open list %q: %v", name, err)
+		return
+	}
+	m.store.Close()
+	m.store = store
+	m.listName = name
+	m.filter = tagFilter{}
+	m.cursor = 0
+	m.history = history{}
+	if m.load() {
+		m.status = fmt.Sprintf("Switched to list %q.", name)
+	}
+	m.loadPrefs()
+}
+
+// moveTodo moves the todo under the cursor to the end of the named list.
+func (m *model) moveTodo(name string) {
+	if name == m.listName {
+		m.status = fmt.Sprintf("Todo is already in list %q.", name)
+		return
+	}
+	if m.readOnly() {
+		m.status = "This list is open read-only, so its todos cannot be moved."
+		return
+	}
+	if m.pending != retryNone {
+		m.status = "Press R to retry the failed load or save before moving todos."
+		return
+	}
+	idx, ok := m.selected()
+	if !ok {
+		return
+	}
+	m.status = fmt.Sprintf("Moved todo to list %q.", name)
+	m.exec(newMoveCmd(m.todos, m.todos[idx].ID, name))
+}
+
+// indent makes the selected todo a subtask of the sibling shown above it.
+func (m *model) indent() {
+	i, ok := m.selected()
+	if !ok {
+		return
+	}
+	if m.filter.active() {
+		m.status = "Clear the filter to rearrange subtasks."
+		return
+	}
+	depth := m.depths[m.cursor]
+	for r := m.cursor - 1; r >= 0 && m.depths[r] >= depth; r-- {
+		if m.depths[r] == depth {
+			parent := m.todos[m.rows[r]]
+			m.status = fmt.Sprintf("Moved under %s.", quoteText(parent.Text))
+			m.exec(updateCmd("indent", m.todos[i], reparent(m.todos[i], parent.ID)))
+			return
+		}
+	}
+	m.status = "No todo above to move this under."
+}
+
+// outdent moves the selected subtask up a level, next to its parent.
+func (m *model) outdent() {
+	i, ok := m.selected()
+	if !ok {
+		return
+	}
+	if m.filter.active() {
+		m.status = "Clear the filter to rearrange subtasks."
+		return
+	}
+	t := m.todos[i]
+	p := indexOfTodo(m.todos, t.ParentID)
+	if p < 0 {
+		m.status = "This todo is already at the top level."
+		return
+	}
+	m.status = "Moved up a level."
+	m.exec(updateCmd("outdent", t, reparent(t, m.todos[p].ParentID)))
+}
+
+// addTodo adds todo, under the todo picked with A if any, and returns to
+// the list.
+func (m *model) addTodo(todo Todo) {
+	todo.ParentID = m.addParent
+	m.status = "Todo added!"
+	if !m.filter.matches(todo) {
+		m.status = "Todo added (hidden by the current filter)."
+	}
+	m.exec(addCmd(todo))
+	m.mode = modeView
+	m.textInput.Reset()
+	m.textInput.Blur()
+}
+
+// priorityIndex is the position of priority in the priority picker.
+func priorityIndex(priority string) int {
+	switch priority {
+	case "urgent":
+		return 0
+	case "low":
+		return 2
+	}
+	return 1
+}
+
+// toggle ticks or unticks the todo at i. Ticking a repeating todo also adds
+// its next occurrence, and undo takes both back together.
+func (m *model) toggle(i int) {
+	t := m.todos[i]
+	t.setDone(!t.Done)
+	next, ok := nextOccurrence(t, time.Now())
+	if !t.Done || !ok {
+		m.status = "Toggled completion."
+		m.exec(updateCmd("toggle", m.todos[i], t))
+		return
+	}
+	// The schedule moves on to the new todo, so unticking and ticking
+	// this one again does not add a second copy.
+	t.Recurrence = ""
+	m.status = fmt.Sprintf("Completed. Next one is due %s.", next.DueDate)
+	m.exec(&batchCmd{
+		verb: "complete " + quoteText(t.Text),
+		cmds: []command{addCmd(next), updateCmd("toggle", m.todos[i], t)},
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+)
+
+// The todo table shows as many rows as fit between what is above and below
+// it. offset is the first row shown; it only moves when the cursor would
+// otherwise leave the screen, or with the paging keys.
+
+// listRows is how many rows of the table fit on screen, or all of them
+// until the terminal's size is known.
+func (m *model) listRows() int {
+	if m.height == 0 {
+		return max(len(m.rows), 1)
+	}
+	// The table has a header, a rule, and a line for the position below
+	// the rows, followed by a blank line; the footer ends in a newline,
+	// which leaves an empty last line.
+	used := strings.Count(m.listTop(), "\n") + strings.Count(m.listFooter(), "\n") + 5
+	if m.paneBelow() {
+		used += m.paneBelowHeight()
+	}
+	return max(m.height-used, 3)
+}
+
+// visibleOffset returns the first row to show so that the cursor is on
+// screen, moving the view as little as possible from m.offset.
+func (m *model) visibleOffset(rows int) int {
+	off := min(m.offset, max(len(m.rows)-rows, 0))
+	if m.cursor < off {
+		off = m.cursor
+	}
+	if m.cursor >= off+rows {
+		off = m.cursor - rows + 1
+	}
+	return max(off, 0)
+}
+
+// scroll moves the cursor and the view by n rows, as the paging keys do,
+// so the cursor stays at the same place on screen where it can.
+func (m *model) scroll(n int) {
+	if len(m.rows) == 0 {
+		return
+	}
+	rows := m.listRows()
+	m.offset = min(max(m.visibleOffset(rows)+n, 0), max(len(m.rows)-rows, 0))
+	m.cursor = min(max(m.cursor+n, 0), len(m.rows)-1)
+	m.offset = m.visibleOffset(rows)
+}
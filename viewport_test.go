@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestVisibleOffset(t *testing.T) {
+	tests := []struct {
+		name                        string
+		total, cursor, offset, rows int
+		want                        int
+	}{
+		{"all rows fit", 5, 4, 0, 10, 0},
+		{"cursor on screen keeps the view", 20, 7, 5, 5, 5},
+		{"cursor above the view", 20, 2, 5, 5, 2},
+		{"cursor below the view", 20, 12, 5, 5, 8},
+		{"view past the end is pulled back", 20, 19, 18, 5, 15},
+		{"list shrank under the view", 3, 0, 10, 5, 0},
+		{"empty list", 0, 0, 4, 5, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &model{rows: make([]int, tt.total), cursor: tt.cursor, offset: tt.offset}
+			if got := m.visibleOffset(tt.rows); got != tt.want {
+				t.Errorf("visibleOffset(%d) = %d, want %d", tt.rows, got, tt.want)
+			}
+		})
+	}
+}